@@ -0,0 +1,160 @@
+package factory
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/nauyey/factory/utils"
+)
+
+const jsonTag = "json"
+
+// AttributesFor generates the values of the fields defined in a factory, and returns them as a map
+// instead of a model instance. It's useful to build request payloads.
+//
+// The keys of the map are the column names of the fields, which are the names in tag `factory`,
+// or the snake case of the field names if the names are omitted in the tags.
+// Like Create, the fields without tag `factory` are skipped.
+// With option WithJSONKeys, the keys are the names in tag `json`.
+// Multilevel fields, like "Address.City", are rendered as nested maps.
+// Associations aren't generated, and callbacks aren't executed.
+//
+// attrs, err := AttributesFor(FactoryModel,
+// 	WithTraits("Chinese"),
+// 	WithField("Name", "new name"),
+// )
+// // attrs => map[string]interface{}{"name": "new name", "country": "China"}
+//
+func AttributesFor(f *Factory, opts ...AttributesOption) (map[string]interface{}, error) {
+	bp := newDefaultBlueprint(f)
+	for _, opt := range opts {
+		if err := opt.applyAttributes(bp); err != nil {
+			return nil, err
+		}
+	}
+
+	return bp.attributes()
+}
+
+// AttributesOption is the option of AttributesFor.
+// It's one of the options of the strategies, like WithTraits and WithField,
+// or an option only accepted by AttributesFor, like WithJSONKeys.
+type AttributesOption interface {
+	applyAttributes(bp *blueprint) error
+}
+
+// attributesOption is the type of options only accepted by AttributesFor.
+type attributesOption func(*blueprint) error
+
+func (opt attributesOption) applyAttributes(bp *blueprint) error {
+	return opt(bp)
+}
+
+func (opt factoryOption) applyAttributes(bp *blueprint) error {
+	return opt(bp)
+}
+
+// WithJSONKeys makes AttributesFor use the names in tag `json` of the fields as the keys of the attributes.
+// The fields with tag `json:"-"` are skipped.
+func WithJSONKeys() attributesOption {
+	return func(bp *blueprint) error {
+		bp.jsonKeys = true
+		return nil
+	}
+}
+
+// attributes generates the values of the fields without associations, and returns them as a map.
+func (bp *blueprint) attributes() (map[string]interface{}, error) {
+	instance := bp.newDefaultInstance()
+	bpFieldValues, err := makeBlueprintFieldValues(bp)
+	if err != nil {
+		return nil, err
+	}
+	evaluator := newEvaluator(bp, instance, StrategyBuild, nil)
+
+	for _, field := range bpFieldValues.associationFieldValues() {
+		bpFieldValues.remove(field.Name)
+	}
+	for _, field := range bpFieldValues.manyToManyFieldValues() {
+		bpFieldValues.remove(field.Name)
+	}
+
+	if err := bp.setInstanceFieldValues(instance, bpFieldValues, evaluator); err != nil {
+		return nil, err
+	}
+
+	attrs := map[string]interface{}{}
+	for _, fieldName := range bpFieldValues.fieldNames {
+		keys, ok := attributeKeys(bp.factory.ModelType, fieldName, bp.jsonKeys)
+		if !ok {
+			continue
+		}
+		setAttribute(attrs, keys, instanceFieldValue(instance, fieldName))
+	}
+
+	return attrs, nil
+}
+
+// attributeKeys returns the keys of each level of the chained field name in model type typ.
+// It returns false if any level of the field is skipped, see attributeKey.
+func attributeKeys(typ reflect.Type, fieldName string, jsonKeys bool) ([]string, bool) {
+	names := chainedFieldNameToFieldNames(fieldName)
+	keys := make([]string, len(names))
+
+	for i, name := range names {
+		if typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+		field, _ := typ.FieldByName(name)
+
+		key, ok := attributeKey(field, jsonKeys)
+		if !ok {
+			return nil, false
+		}
+		keys[i] = key
+		typ = field.Type
+	}
+
+	return keys, true
+}
+
+// attributeKey returns the key of the struct field.
+// It's the column name of the field, or the name in tag `json` if jsonKeys is true.
+// It returns false if the field is skipped by tag `json:"-"`, or the field isn't saved into database without tag `factory`.
+func attributeKey(field reflect.StructField, jsonKeys bool) (string, bool) {
+	if jsonKeys {
+		tag := field.Tag.Get(jsonTag)
+		name := strings.TrimSpace(strings.Split(tag, ",")[0])
+		if name == "-" && tag == "-" {
+			return "", false
+		}
+		if name == "" {
+			return field.Name, true
+		}
+		return name, true
+	}
+
+	tag, ok := field.Tag.Lookup(factoryTag)
+	if !ok {
+		return "", false
+	}
+	name := strings.TrimSpace(strings.Split(tag, ",")[0])
+	if name == "" {
+		return utils.SnakeCase(field.Name), true
+	}
+	return name, true
+}
+
+// setAttribute sets value into attrs by keys, and creates the nested maps if not exist.
+func setAttribute(attrs map[string]interface{}, keys []string, value interface{}) {
+	for _, key := range keys[:len(keys)-1] {
+		nested, ok := attrs[key].(map[string]interface{})
+		if !ok {
+			nested = map[string]interface{}{}
+			attrs[key] = nested
+		}
+		attrs = nested
+	}
+
+	attrs[keys[len(keys)-1]] = value
+}
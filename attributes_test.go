@@ -0,0 +1,89 @@
+package factory_test
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/nauyey/factory"
+	"github.com/nauyey/factory/def"
+)
+
+type testAddress struct {
+	City   string `factory:"city_name" json:"cityName"`
+	Street string
+}
+
+type testProfile struct {
+	ID       int64       `factory:"id,primary" json:"id"`
+	Name     string      `factory:"" json:"name"`
+	NickName string      `factory:"" json:"nickName,omitempty"`
+	Password string      `factory:"password" json:"-"`
+	Address  testAddress `factory:"address"`
+	Remark   string
+	AuthorID int64     `factory:"author_id" json:"authorID"`
+	Author   *testUser `json:"author"`
+}
+
+func TestAttributesFor(t *testing.T) {
+	userFactory := def.NewFactory(testUser{}, "",
+		def.Field("Name", "author name"),
+	)
+	profileFactory := def.NewFactory(testProfile{}, "",
+		def.SequenceField("ID", 1, func(n int64) (interface{}, error) {
+			return n, nil
+		}),
+		def.Field("Name", "test name"),
+		def.DynamicField("NickName", func(model interface{}) (interface{}, error) {
+			return "nick " + model.(*testProfile).Name, nil
+		}),
+		def.Field("Password", "secret"),
+		def.Field("Address.City", "Beijing"),
+		def.Field("Remark", "not saved"),
+		def.Association("Author", "AuthorID", "ID", userFactory),
+		def.Trait("street",
+			def.Field("Address.Street", "Main Street"),
+		),
+	)
+
+	// Test AttributesFor with column names
+	attrs, err := AttributesFor(profileFactory, WithTraits("street"), WithField("Name", "new name"))
+	if err != nil {
+		t.Fatalf("AttributesFor failed with error: %v", err)
+	}
+	want := map[string]interface{}{
+		"id":        int64(1),
+		"name":      "new name",
+		"nick_name": "nick new name",
+		"password":  "secret",
+		"address": map[string]interface{}{
+			"city_name": "Beijing",
+		},
+	}
+	if !reflect.DeepEqual(attrs, want) {
+		t.Errorf("AttributesFor failed with attrs=%v, want attrs=%v", attrs, want)
+	}
+
+	// Test AttributesFor with JSON keys
+	attrs, err = AttributesFor(profileFactory, WithJSONKeys(), WithAssociationID("Author", int64(10)))
+	if err != nil {
+		t.Fatalf("AttributesFor failed with error: %v", err)
+	}
+	want = map[string]interface{}{
+		"id":       int64(2),
+		"name":     "test name",
+		"nickName": "nick test name",
+		"Address": map[string]interface{}{
+			"cityName": "Beijing",
+		},
+		"Remark":   "not saved",
+		"authorID": int64(10),
+	}
+	if !reflect.DeepEqual(attrs, want) {
+		t.Errorf("AttributesFor with JSON keys failed with attrs=%v, want attrs=%v", attrs, want)
+	}
+
+	// Test AttributesFor with option error
+	if _, err := AttributesFor(profileFactory, WithTraits("undefined")); err == nil {
+		t.Errorf("AttributesFor with undefined trait should return error")
+	}
+}
@@ -1,67 +1,165 @@
 package factory
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
+
+	"github.com/nauyey/factory/utils"
 )
 
 const (
-	invalidDeleteInstanceTypeErr = "can't delete type(%s) instance, want type(%s) instance"
+	invalidDeleteInstanceTypeErr   = "can't delete type(%s) instance, want type(%s) instance"
+	invalidJoinPrimaryKeyErr       = "table %s must have exactly one primary key to be joined in many-to-many associations"
+	undefinedAssociationFactoryErr = "no factory is chosen for association %s"
+	undefinedSequenceFieldErr      = "field %s of type %s factory isn't a sequence field"
+	invalidGeneratedValueErr       = "invalid generated value of field %s: %v"
+	invalidFieldValueErr           = "invalid value of field %s: %v"
+	invalidAssociationOptionErr    = "invalid options of association %s: %v"
 )
 
 // blueprint represents the runtime instance of a specific Factory model defined before.
 // It is created by the function Create and Build.
 // A model struct instance will be generated from it.
 type blueprint struct {
-	factory     *Factory
-	table       *table
-	traits      []string
-	filedValues map[string]interface{}
+	factory              *Factory
+	table                *table
+	traits               []string
+	filedValues          []*FieldDefinition
+	transients           map[string]interface{}
+	associationOverrides map[string]*associationOverride
+	sequenceStarts       map[string]int64
+	jsonKeys             bool
+	// ctx and executor are set by WithContext and WithExecutor
+	ctx      context.Context
+	executor Executor
+	// strategy is the name of the user-defined strategy generating the model instance
+	strategy string
+	// indexedFields, indexedTraits and eachFuncs customize each model instance generated by the slice strategies
+	indexedFields []*indexedField
+	indexedTraits []func(i int) []string
+	eachFuncs     []func(i int, model interface{}) error
+	// custom is the user-defined strategy generating the model instance
+	custom Strategy
+	// checkReads makes the evaluator check the dynamic fields don't read the fields evaluated after them, set by Lint
+	checkReads bool
+}
+
+// associationOverride represents the overrides of an association set when the model instance is generated.
+type associationOverride struct {
+	factory  *Factory
+	strategy string
+	options  []factoryOption
+	id       interface{}
+	hasID    bool
+	instance interface{}
+	skipped  bool
+}
+
+// skippedFieldValue represents a field which is left as zero value, set by WithoutField.
+type skippedFieldValue struct{}
+
+// context returns the context set by WithContext, or context.Background() if it isn't set.
+func (bp *blueprint) context() context.Context {
+	if bp.ctx == nil {
+		return context.Background()
+	}
+	return bp.ctx
+}
+
+// dbExecutor returns the executor set by WithExecutor, or the database connection set by SetDB.
+func (bp *blueprint) dbExecutor() Executor {
+	if bp.executor != nil {
+		return bp.executor
+	}
+	if db := getDB(); db != nil {
+		return db
+	}
+	return nil
+}
+
+// associationOverride returns the overrides of association name, and creates it if not exists.
+func (bp *blueprint) associationOverride(name string) *associationOverride {
+	if bp.associationOverrides == nil {
+		bp.associationOverrides = map[string]*associationOverride{}
+	}
+	if _, ok := bp.associationOverrides[name]; !ok {
+		bp.associationOverrides[name] = &associationOverride{}
+	}
+	return bp.associationOverrides[name]
 }
 
 // create creates a model struct instance and save it into database.
+// Callback BeforeBuild will be executed before the fields of the model struct instance been set.
 // Callback BeforeCreate will be executed after the model struct instance been created
 // and before the instance been saved into database.
 // Callback AfterCreate will be execute after the model struct instance been saved into database.
-func (bp *blueprint) create(db *sql.DB) (interface{}, error) {
+func (bp *blueprint) create(db Executor) (interface{}, error) {
+	if db == nil {
+		return nil, fmt.Errorf(undefinedDBErr)
+	}
+
 	instance := bp.newDefaultInstance()
-	bpFieldValues := makeBlueprintFieldValues(bp)
+	bpFieldValues, err := makeBlueprintFieldValues(bp)
+	if err != nil {
+		return nil, err
+	}
+	evaluator := newEvaluator(bp, instance, StrategyCreate, db)
 
-	if err := createInstanceAssociations(db, instance, bpFieldValues.associationFieldValues()); err != nil {
+	if err := bp.executeBeforeBuildCallbacks(instance, evaluator); err != nil {
+		return nil, err
+	}
+	associationFields, err := bp.associationFieldValues(bpFieldValues)
+	if err != nil {
+		return nil, err
+	}
+	if err := generateInstanceAssociations(bp.context(), db, StrategyCreate, instance, associationFields); err != nil {
+		return nil, err
+	}
+	if err := bp.seedSequencesFromDB(db, bpFieldValues); err != nil {
 		return nil, err
 	}
-	if err := bp.setInstanceFieldValues(instance, bpFieldValues); err != nil {
+	if err := bp.setInstanceFieldValues(instance, bpFieldValues, evaluator); err != nil {
 		return nil, err
 	}
 
 	// callbacks
 	// execute after build callback
-	if err := bp.executeAfterBuildCallbacks(instance); err != nil {
+	if err := bp.executeAfterBuildCallbacks(instance, evaluator); err != nil {
 		return nil, err
 	}
 	// execute before create callback
-	if err := bp.executeBeforeCreateCallbacks(instance); err != nil {
+	if err := bp.executeBeforeCreateCallbacks(instance, evaluator); err != nil {
 		return nil, err
 	}
 
-	if err := bp.createInstance(db, instance); err != nil {
+	if err := bp.persist(db, instance, evaluator); err != nil {
+		return nil, err
+	}
+	if err := createInstanceManyToManyAssociations(bp.context(), db, bp.table, instance, bpFieldValues.manyToManyFieldValues()); err != nil {
 		return nil, err
 	}
 
 	// callbacks
 	// execute after build callback
-	if err := bp.executeAfterCreateCallbacks(instance); err != nil {
+	if err := bp.executeAfterCreateCallbacks(instance, evaluator); err != nil {
 		return nil, err
 	}
 
-	return instance.Addr().Interface(), nil
+	return bp.complete(instance, evaluator)
 }
 
 // delete deletes a blueprint created instance from database.
 // It uses the primary key related field values of the instance.
-func (bp *blueprint) delete(db *sql.DB, instance interface{}) error {
+// The join table rows of the many-to-many associations are deleted too.
+// Callback BeforeDelete and AfterDelete will be executed before and after the instance been deleted.
+func (bp *blueprint) delete(db Executor, instance interface{}) error {
+	if db == nil {
+		return fmt.Errorf(undefinedDBErr)
+	}
+
 	instanceType := reflect.TypeOf(instance)
 	instanceValue := reflect.ValueOf(instance)
 	if instanceType.Kind() == reflect.Ptr {
@@ -71,6 +169,21 @@ func (bp *blueprint) delete(db *sql.DB, instance interface{}) error {
 	if instanceType != bp.factory.ModelType {
 		return fmt.Errorf(invalidDeleteInstanceTypeErr, instanceType.Name(), bp.factory.ModelType.Name())
 	}
+	// the callbacks need an addressable instance
+	if !instanceValue.CanAddr() {
+		addressable := reflect.New(instanceType).Elem()
+		addressable.Set(instanceValue)
+		instanceValue = addressable
+	}
+	evaluator := newEvaluator(bp, instanceValue, StrategyDelete, db)
+
+	if err := bp.executeBeforeDeleteCallbacks(instanceValue, evaluator); err != nil {
+		return err
+	}
+
+	if err := deleteInstanceManyToManyAssociations(bp.context(), db, bp.table, instanceValue, factoryManyToManyFieldValues(bp.factory)); err != nil {
+		return err
+	}
 
 	primaryValues := []interface{}{}
 	for _, col := range bp.table.columns {
@@ -79,29 +192,139 @@ func (bp *blueprint) delete(db *sql.DB, instance interface{}) error {
 		}
 	}
 
-	_, err := db.Exec(deleteSQL(bp.table.name, bp.table.getPrimaryKeys()), primaryValues...)
-	return err
+	if err := deleteRow(bp.context(), db, deleteSQL(bp.table.name, bp.table.getPrimaryKeys()), primaryValues...); err != nil {
+		return err
+	}
+
+	return bp.executeAfterDeleteCallbacks(instanceValue, evaluator)
 }
 
 // build creates a model struct instance but won't save into database.
+// Callback BeforeBuild will be executed before the fields of the model struct instance been set.
 // Callback AfterBuild will be execute after the model struct instance been created.
 func (bp *blueprint) build() (interface{}, error) {
 	instance := bp.newDefaultInstance()
-	bpFieldValues := makeBlueprintFieldValues(bp)
+	bpFieldValues, err := makeBlueprintFieldValues(bp)
+	if err != nil {
+		return nil, err
+	}
+	evaluator := newEvaluator(bp, instance, StrategyBuild, bp.dbExecutor())
+
+	if err := bp.executeBeforeBuildCallbacks(instance, evaluator); err != nil {
+		return nil, err
+	}
+	associationFields, err := bp.associationFieldValues(bpFieldValues)
+	if err != nil {
+		return nil, err
+	}
+	if err := generateInstanceAssociations(bp.context(), bp.dbExecutor(), StrategyBuild, instance, associationFields); err != nil {
+		return nil, err
+	}
+	if err := generateInstanceManyToManyAssociations(bp.context(), StrategyBuild, instance, bpFieldValues.manyToManyFieldValues()); err != nil {
+		return nil, err
+	}
+
+	if err := bp.setInstanceFieldValues(instance, bpFieldValues, evaluator); err != nil {
+		return nil, err
+	}
+
+	if err := bp.executeAfterBuildCallbacks(instance, evaluator); err != nil {
+		return nil, err
+	}
+
+	return bp.complete(instance, evaluator)
+}
+
+// stub creates a model struct instance as if it has been saved into database, but won't touch database.
+// The primary key fields are set with unique fake ids if they aren't set by the factory,
+// and the associations are stubbed too.
+// Callback AfterStub will be execute after the model struct instance been stubbed.
+func (bp *blueprint) stub() (interface{}, error) {
+	instance := bp.newDefaultInstance()
+	bpFieldValues, err := makeBlueprintFieldValues(bp)
+	if err != nil {
+		return nil, err
+	}
+	evaluator := newEvaluator(bp, instance, StrategyStub, nil)
 
-	if err := buildInstanceAssociations(instance, bpFieldValues.associationFieldValues()); err != nil {
+	associationFields, err := bp.associationFieldValues(bpFieldValues)
+	if err != nil {
+		return nil, err
+	}
+	if err := generateInstanceAssociations(bp.context(), nil, StrategyStub, instance, associationFields); err != nil {
+		return nil, err
+	}
+	if err := generateInstanceManyToManyAssociations(bp.context(), StrategyStub, instance, bpFieldValues.manyToManyFieldValues()); err != nil {
 		return nil, err
 	}
 
-	if err := bp.setInstanceFieldValues(instance, bpFieldValues); err != nil {
+	if err := bp.setInstanceFieldValues(instance, bpFieldValues, evaluator); err != nil {
 		return nil, err
 	}
+	stubInstancePrimaryKeys(newTable(bp.factory), instance)
 
-	if err := bp.executeAfterBuildCallbacks(instance); err != nil {
+	if err := bp.executeAfterStubCallbacks(instance, evaluator); err != nil {
 		return nil, err
 	}
 
-	return instance.Addr().Interface(), nil
+	return bp.complete(instance, evaluator)
+}
+
+// complete returns the generated model instance, completed by the result of the user-defined strategy if set.
+// It returns error if the result isn't a pointer of the model struct.
+func (bp *blueprint) complete(instance reflect.Value, e *Evaluator) (interface{}, error) {
+	if bp.custom == nil {
+		return instance.Addr().Interface(), nil
+	}
+
+	result, err := bp.custom.Result(instance.Addr().Interface(), e)
+	if err != nil {
+		return nil, err
+	}
+	if value := reflect.ValueOf(result); value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Type() != bp.factory.ModelType {
+		return nil, fmt.Errorf(invalidResultErr, bp.strategy, reflect.TypeOf(result), bp.factory.ModelType.Name())
+	}
+	return result, nil
+}
+
+// executeStrategyCallback calls the callback of the user-defined strategy in phase if it implements CallbackStrategy.
+func (bp *blueprint) executeStrategyCallback(phase string, ptrIface interface{}, e *Evaluator) error {
+	s, ok := bp.custom.(CallbackStrategy)
+	if !ok {
+		return nil
+	}
+	return s.Callback(phase, ptrIface, e)
+}
+
+// associationFieldValues returns the association field values,
+// whose strategies are chosen by the user-defined strategy if it implements AssociationStrategy.
+func (bp *blueprint) associationFieldValues(bpFieldValues *blueprintFieldValues) ([]*FieldDefinition, error) {
+	fields := bpFieldValues.associationFieldValues()
+
+	s, ok := bp.custom.(AssociationStrategy)
+	if !ok {
+		return fields, nil
+	}
+	for _, field := range fields {
+		strategy := s.Association(field.Name)
+		if strategy == "" {
+			continue
+		}
+		if strategy != StrategyBuild && strategy != StrategyCreate {
+			return nil, fmt.Errorf(invalidStrategyErr, strategy, field.Name)
+		}
+		field.Value = field.Value.(*AssociationFieldValue).withStrategy(strategy)
+	}
+	return fields, nil
+}
+
+// persist saves the model instance into database,
+// or by the user-defined strategy if it implements PersistStrategy.
+func (bp *blueprint) persist(db Executor, instance reflect.Value, e *Evaluator) error {
+	if s, ok := bp.custom.(PersistStrategy); ok {
+		return s.Persist(db, instance.Addr().Interface(), e)
+	}
+	return bp.createInstance(db, instance)
 }
 
 func (bp *blueprint) newDefaultInstance() reflect.Value {
@@ -109,31 +332,76 @@ func (bp *blueprint) newDefaultInstance() reflect.Value {
 	return reflect.New(f.ModelType).Elem()
 }
 
-func (bp *blueprint) setInstanceFieldValues(instance reflect.Value, bpFieldValues blueprintFieldValues) error {
-	for fieldName, fieldValue := range bpFieldValues.filedValues() {
-		setInstanceFieldValue(instance, fieldName, fieldValue)
+// setInstanceFieldValues sets the field values of the instance.
+// The fields with static values are set first, then the sequence fields.
+// The dynamic fields and dependent fields are evaluated at last,
+// each of them after the fields it reads by the Evaluator.
+// The fields of each kind are set in the order they are defined.
+func (bp *blueprint) setInstanceFieldValues(instance reflect.Value, bpFieldValues *blueprintFieldValues, e *Evaluator) error {
+	for _, field := range bpFieldValues.filedValues() {
+		if err := setInstanceFieldValue(instance, field.Name, field.Value); err != nil {
+			return err
+		}
+	}
+
+	if err := bp.checkSequenceStarts(bpFieldValues); err != nil {
+		return err
 	}
 
-	for fieldName, sequenceValue := range bpFieldValues.sequenceFieldValues() {
-		fieldValue, err := sequenceValue.value()
+	for _, field := range bpFieldValues.sequenceFieldValues() {
+		fieldValue, err := bp.sequenceFieldValue(field.Name, field.Value.(*sequenceValue))
 		if err != nil {
 			return err
 		}
-		setInstanceFieldValue(instance, fieldName, fieldValue)
+		if err := setInstanceGeneratedFieldValue(instance, field.Name, fieldValue); err != nil {
+			return err
+		}
 	}
 
-	for fieldName, dynamicFieldValue := range bpFieldValues.dynamicFieldValues() {
-		fieldValue, err := dynamicFieldValue(instance.Addr().Interface())
-		if err != nil {
+	for _, field := range bpFieldValues.generatedFieldValues() {
+		e.addGenerator(field.Name, field.Value)
+	}
+
+	return e.evaluateAll()
+}
+
+// checkSequenceStarts checks the fields set by WithSequenceStart are sequence fields.
+func (bp *blueprint) checkSequenceStarts(bpFieldValues *blueprintFieldValues) error {
+	for _, fieldName := range sortedFieldNames(bp.sequenceStarts) {
+		fieldValue, _ := bpFieldValues.get(fieldName)
+		if _, ok := fieldValue.(*sequenceValue); !ok {
+			return fmt.Errorf(undefinedSequenceFieldErr, fieldName, bp.factory.ModelType.Name())
+		}
+	}
+
+	return nil
+}
+
+// sequenceFieldValue generates the value of a sequence field.
+// The fields set by WithSequenceStart use the numbers counted by the blueprint from their starts,
+// so the sequences of the factory aren't moved. Otherwise, the next numbers of the sequences are used.
+func (bp *blueprint) sequenceFieldValue(name string, seqValue *sequenceValue) (interface{}, error) {
+	n, ok := bp.sequenceStarts[name]
+	if !ok {
+		return seqValue.value()
+	}
+
+	bp.sequenceStarts[name] = n + 1
+	return seqValue.valueAt(n)
+}
+
+// seedSequencesFromDB seeds the sequences defined by def.SequenceFromDB above the maximum values of their columns in database.
+func (bp *blueprint) seedSequencesFromDB(db Executor, bpFieldValues *blueprintFieldValues) error {
+	for _, field := range bpFieldValues.sequenceFieldValues() {
+		if err := field.Value.(*sequenceValue).seedFromDB(bp.context(), db, bp.table.name); err != nil {
 			return err
 		}
-		setInstanceFieldValue(instance, fieldName, fieldValue)
 	}
 
 	return nil
 }
 
-func (bp *blueprint) createInstance(db *sql.DB, instance reflect.Value) error {
+func (bp *blueprint) createInstance(db Executor, instance reflect.Value) error {
 	var (
 		fields                  []string
 		insertFields            []string
@@ -154,7 +422,7 @@ func (bp *blueprint) createInstance(db *sql.DB, instance reflect.Value) error {
 	}
 
 	// insert
-	_, err := insertRow(db, insertSQL(tbl.name, insertFields), values...)
+	_, err := insertRow(bp.context(), db, insertSQL(tbl.name, insertFields), values...)
 	if err != nil {
 		return err
 	}
@@ -169,7 +437,7 @@ func (bp *blueprint) createInstance(db *sql.DB, instance reflect.Value) error {
 		primaryKeyValues[i] = instance.Field(col.originalModelIndex).Interface()
 	}
 
-	err = selectRow(db, selectSQL(tbl.name, fields, primaryKeys), primaryKeyValues, queryFieldValuePointers)
+	err = selectRow(bp.context(), db, selectSQL(tbl.name, fields, primaryKeys), primaryKeyValues, queryFieldValuePointers)
 	if err != nil {
 		return err
 	}
@@ -186,163 +454,429 @@ func (bp *blueprint) updateModelInstanceField(instance reflect.Value, index int,
 	instance.Field(index).Set(reflect.ValueOf(value).Elem())
 }
 
-func (bp *blueprint) executeAfterBuildCallbacks(modelInstance reflect.Value) error {
+func (bp *blueprint) executeBeforeBuildCallbacks(modelInstance reflect.Value, e *Evaluator) error {
+	ptrIface := modelInstance.Addr().Interface()
+
+	// execute trait before build callbacks in reverse order of traits
+	for i := len(bp.traits) - 1; i >= 0; i-- {
+		trait := bp.traits[i]
+		traitFactory := bp.factory.Traits[trait]
+		if err := executeCallbacks(ptrIface, e, traitFactory.BeforeBuildCallbacks); err != nil {
+			return err
+		}
+	}
+
+	// execute before build callbacks in bp.facotry
+	if err := executeCallbacks(ptrIface, e, bp.factory.BeforeBuildCallbacks); err != nil {
+		return err
+	}
+
+	// execute the callback of the user-defined strategy
+	return bp.executeStrategyCallback(CallbackBeforeBuild, ptrIface, e)
+}
+
+func (bp *blueprint) executeAfterBuildCallbacks(modelInstance reflect.Value, e *Evaluator) error {
 	ptrIface := modelInstance.Addr().Interface()
 
 	// execute trait after build callbacks in reverse order of traits
 	for i := len(bp.traits) - 1; i >= 0; i-- {
 		trait := bp.traits[i]
 		traitFactory := bp.factory.Traits[trait]
-		if err := executeCallbacks(ptrIface, traitFactory.AfterBuildCallbacks); err != nil {
+		if err := executeCallbacks(ptrIface, e, traitFactory.AfterBuildCallbacks); err != nil {
 			return err
 		}
 	}
 
 	// execute after build callbacks in bp.facotry
-	return executeCallbacks(ptrIface, bp.factory.AfterBuildCallbacks)
+	if err := executeCallbacks(ptrIface, e, bp.factory.AfterBuildCallbacks); err != nil {
+		return err
+	}
+
+	// execute the callback of the user-defined strategy
+	return bp.executeStrategyCallback(CallbackAfterBuild, ptrIface, e)
 }
 
-func (bp *blueprint) executeBeforeCreateCallbacks(modelInstance reflect.Value) error {
+func (bp *blueprint) executeAfterStubCallbacks(modelInstance reflect.Value, e *Evaluator) error {
+	ptrIface := modelInstance.Addr().Interface()
+
+	// execute trait after stub callbacks in reverse order of traits
+	for i := len(bp.traits) - 1; i >= 0; i-- {
+		trait := bp.traits[i]
+		traitFactory := bp.factory.Traits[trait]
+		if err := executeCallbacks(ptrIface, e, traitFactory.AfterStubCallbacks); err != nil {
+			return err
+		}
+	}
+
+	// execute after stub callbacks in bp.facotry
+	if err := executeCallbacks(ptrIface, e, bp.factory.AfterStubCallbacks); err != nil {
+		return err
+	}
+
+	// execute the callback of the user-defined strategy
+	return bp.executeStrategyCallback(CallbackAfterStub, ptrIface, e)
+}
+
+func (bp *blueprint) executeBeforeCreateCallbacks(modelInstance reflect.Value, e *Evaluator) error {
 	ptrIface := modelInstance.Addr().Interface()
 
 	// execute trait before create callbacks in reverse order of traits
 	for i := len(bp.traits) - 1; i >= 0; i-- {
 		trait := bp.traits[i]
 		traitFactory := bp.factory.Traits[trait]
-		if err := executeCallbacks(ptrIface, traitFactory.BeforeCreateCallbacks); err != nil {
+		if err := executeCallbacks(ptrIface, e, traitFactory.BeforeCreateCallbacks); err != nil {
 			return err
 		}
 	}
 
 	// execute before create callbacks in bp.facotry
-	return executeCallbacks(ptrIface, bp.factory.BeforeCreateCallbacks)
+	if err := executeCallbacks(ptrIface, e, bp.factory.BeforeCreateCallbacks); err != nil {
+		return err
+	}
+
+	// execute the callback of the user-defined strategy
+	return bp.executeStrategyCallback(CallbackBeforeCreate, ptrIface, e)
 }
 
-func (bp *blueprint) executeAfterCreateCallbacks(modelInstance reflect.Value) error {
+func (bp *blueprint) executeAfterCreateCallbacks(modelInstance reflect.Value, e *Evaluator) error {
 	ptrIface := modelInstance.Addr().Interface()
 
 	// execute trait after create callbacks in reverse order of traits
 	for i := len(bp.traits) - 1; i >= 0; i-- {
 		trait := bp.traits[i]
 		traitFactory := bp.factory.Traits[trait]
-		if err := executeCallbacks(ptrIface, traitFactory.AfterCreateCallbacks); err != nil {
+		if err := executeCallbacks(ptrIface, e, traitFactory.AfterCreateCallbacks); err != nil {
 			return err
 		}
 	}
 
 	// execute after create callbacks in bp.facotry
-	return executeCallbacks(ptrIface, bp.factory.AfterCreateCallbacks)
+	if err := executeCallbacks(ptrIface, e, bp.factory.AfterCreateCallbacks); err != nil {
+		return err
+	}
+
+	// execute the callback of the user-defined strategy
+	return bp.executeStrategyCallback(CallbackAfterCreate, ptrIface, e)
+}
+
+// generateInstanceAssociations generates the associations of an instance generated by parentStrategy.
+// Each association is generated by its own strategy if it has one, otherwise by parentStrategy.
+func generateInstanceAssociations(ctx context.Context, db Executor, parentStrategy string, instance reflect.Value, associationFieldValues []*FieldDefinition) error {
+	for _, field := range associationFieldValues {
+		fieldName, fieldValue := field.Name, field.Value.(*AssociationFieldValue)
+		if fieldValue.OriginalFactory == nil {
+			return fmt.Errorf(undefinedAssociationFactoryErr, fieldName)
+		}
+
+		var (
+			associationInterface interface{}
+			err                  error
+		)
+		strategy := fieldValue.strategy(parentStrategy)
+		// all the associations of stubbed instances are stubbed
+		if parentStrategy == StrategyStub {
+			strategy = StrategyStub
+		}
+
+		var bp *blueprint
+		if strategy == StrategyCreate {
+			bp = newBlueprintFromAssociationFieldValueForCreateAndDelete(fieldValue)
+		} else {
+			bp = newDefaultBlueprintFromAssociationFieldValue(fieldValue)
+		}
+		bp.ctx = ctx
+		if err := applyFactoryOptions(bp, fieldValue.options); err != nil {
+			return fmt.Errorf(invalidAssociationOptionErr, fieldName, err)
+		}
+
+		switch strategy {
+		case StrategyCreate:
+			associationInterface, err = bp.create(db)
+		case StrategyStub:
+			associationInterface, err = bp.stub()
+		default:
+			associationInterface, err = bp.build()
+		}
+		if err != nil {
+			return err
+		}
+		if err := setInstanceAssociationFieldValues(instance, fieldName, fieldValue, associationInterface); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setInstanceAssociationFieldValues sets the association field, the reference field
+// and the type field of polymorphic association by the association instance.
+// It returns error if the association instance can't be set into the fields.
+func setInstanceAssociationFieldValues(instance reflect.Value, fieldName string, fieldValue *AssociationFieldValue, associationInterface interface{}) error {
+	if err := setInstanceFieldValue(instance, fieldName, associationInterface); err != nil {
+		return err
+	}
+
+	// set instance reference field value
+	value := reflect.ValueOf(associationInterface)
+	if value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	// FIXME: value.FieldByName(fieldValue.AssociationReferenceField) can't handle mix field name
+	referenceValue := value.FieldByName(fieldValue.AssociationReferenceField)
+	if !referenceValue.IsValid() {
+		return fmt.Errorf(invalidFieldNameErr, fieldValue.AssociationReferenceField, value.Type().Name())
+	}
+	if err := setInstanceFieldValue(instance, fieldValue.ReferenceField, referenceValue.Interface()); err != nil {
+		return err
+	}
+
+	// set instance type field value of polymorphic association
+	if fieldValue.TypeField != "" {
+		return setInstanceFieldValue(instance, fieldValue.TypeField, fieldValue.typeValue(instance.Type(), value.Type()))
+	}
+	return nil
+}
+
+func generateInstanceManyToManyAssociations(ctx context.Context, strategy string, instance reflect.Value, manyToManyFieldValues []*FieldDefinition) error {
+	for _, field := range manyToManyFieldValues {
+		fieldName, fieldValue := field.Name, field.Value.(*ManyToManyFieldValue)
+		associationBlueprint := newDefaultBlueprintFromManyToManyFieldValue(fieldValue)
+		associationBlueprint.ctx = ctx
+		sliceValue, isPtrElem := makeInstanceSliceFieldValue(instance, fieldName, fieldValue.Count)
+
+		for i := 0; i < fieldValue.Count; i++ {
+			var (
+				associationInterface interface{}
+				err                  error
+			)
+			switch strategy {
+			case StrategyStub:
+				associationInterface, err = associationBlueprint.stub()
+			default:
+				associationInterface, err = associationBlueprint.build()
+			}
+			if err != nil {
+				return err
+			}
+			sliceValue = appendSliceValue(sliceValue, isPtrElem, reflect.ValueOf(associationInterface))
+		}
+		if err := setInstanceFieldValue(instance, fieldName, sliceValue.Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func buildInstanceAssociations(instance reflect.Value, associationFieldValues map[string]*AssociationFieldValue) error {
-	for fieldName, fieldValue := range associationFieldValues {
-		associationBlueprint := newDefaultBlueprintFromAssociationFieldValue(fieldValue)
-		associationInterface, err := associationBlueprint.build()
+// createInstanceManyToManyAssociations creates the associated instances of many-to-many associations,
+// and links them to the instance by inserting rows into the join tables.
+// The instance must have been saved into database before, because its primary key is needed by the join rows.
+func createInstanceManyToManyAssociations(ctx context.Context, db Executor, tbl *table, instance reflect.Value, manyToManyFieldValues []*FieldDefinition) error {
+	for _, field := range manyToManyFieldValues {
+		fieldName, fieldValue := field.Name, field.Value.(*ManyToManyFieldValue)
+		instanceKey, err := singlePrimaryKeyValue(tbl, instance)
 		if err != nil {
 			return err
 		}
-		setInstanceFieldValue(instance, fieldName, associationInterface)
 
-		// set instance reference field value
-		value := reflect.ValueOf(associationInterface)
-		if value.Kind() == reflect.Ptr {
-			value = value.Elem()
+		associationBlueprint := newBlueprintFromManyToManyFieldValueForCreate(fieldValue)
+		associationBlueprint.ctx = ctx
+		sliceValue, isPtrElem := makeInstanceSliceFieldValue(instance, fieldName, fieldValue.Count)
+
+		for i := 0; i < fieldValue.Count; i++ {
+			associationInterface, err := associationBlueprint.create(db)
+			if err != nil {
+				return err
+			}
+			associationValue := reflect.ValueOf(associationInterface)
+
+			associationKey, err := singlePrimaryKeyValue(associationBlueprint.table, associationValue.Elem())
+			if err != nil {
+				return err
+			}
+			joinFields := []string{fieldValue.ForeignKey, fieldValue.AssociationForeignKey}
+			if _, err := insertRow(ctx, db, insertSQL(fieldValue.JoinTable, joinFields), instanceKey, associationKey); err != nil {
+				return err
+			}
+
+			sliceValue = appendSliceValue(sliceValue, isPtrElem, associationValue)
+		}
+		if err := setInstanceFieldValue(instance, fieldName, sliceValue.Interface()); err != nil {
+			return err
 		}
-		// FIXME: value.FieldByName(fieldValue.AssociationReferenceField) can't handle mix field name
-		setInstanceFieldValue(instance, fieldValue.ReferenceField, value.FieldByName(fieldValue.AssociationReferenceField).Interface())
 	}
 	return nil
 }
 
-// TODO: most of the code is duplicated with buildInstanceAssociations
-func createInstanceAssociations(db *sql.DB, instance reflect.Value, associationFieldValues map[string]*AssociationFieldValue) error {
-	for fieldName, fieldValue := range associationFieldValues {
-		associationBlueprint := newBlueprintFromAssociationFieldValueForCreateAndDelete(fieldValue)
-		associationInterface, err := associationBlueprint.create(db)
+// deleteInstanceManyToManyAssociations deletes the join table rows which link the instance to its many-to-many associations.
+// The associated instances themselves are kept in database.
+func deleteInstanceManyToManyAssociations(ctx context.Context, db Executor, tbl *table, instance reflect.Value, manyToManyFieldValues []*ManyToManyFieldValue) error {
+	for _, fieldValue := range manyToManyFieldValues {
+		instanceKey, err := singlePrimaryKeyValue(tbl, instance)
 		if err != nil {
 			return err
 		}
-		setInstanceFieldValue(instance, fieldName, associationInterface)
 
-		value := reflect.ValueOf(associationInterface)
-		if value.Kind() == reflect.Ptr {
-			value = value.Elem()
+		if err := deleteRow(ctx, db, deleteSQL(fieldValue.JoinTable, []string{fieldValue.ForeignKey}), instanceKey); err != nil {
+			return err
 		}
-		// FIXME: value.FieldByName(fieldValue.AssociationReferenceField) can't handle mix field name
-		setInstanceFieldValue(instance, fieldValue.ReferenceField, value.FieldByName(fieldValue.AssociationReferenceField).Interface())
 	}
 	return nil
 }
 
-type blueprintFieldValues map[string]interface{}
+// factoryManyToManyFieldValues collects the many-to-many associations defined in the factory and all its traits.
+func factoryManyToManyFieldValues(f *Factory) []*ManyToManyFieldValue {
+	var fieldValues []*ManyToManyFieldValue
 
-func (bpFieldValues blueprintFieldValues) associationFieldValues() map[string]*AssociationFieldValue {
-	fieldValues := map[string]*AssociationFieldValue{}
+	factories := []*Factory{f}
+	for _, trait := range sortedFieldNames(f.Traits) {
+		factories = append(factories, f.Traits[trait])
+	}
 
-	for fieldName, fieldValue := range bpFieldValues {
-		if value, ok := fieldValue.(*AssociationFieldValue); ok {
-			fieldValues[fieldName] = value
+	for _, definedFactory := range factories {
+		for _, field := range definedFactory.Fields {
+			if fieldValue, ok := field.Value.(*ManyToManyFieldValue); ok {
+				fieldValues = append(fieldValues, fieldValue)
+			}
 		}
 	}
 
 	return fieldValues
 }
 
-func (bpFieldValues blueprintFieldValues) filedValues() map[string]interface{} {
-	fieldValues := map[string]interface{}{}
+// singlePrimaryKeyValue returns the value of the only primary key field of the instance.
+func singlePrimaryKeyValue(tbl *table, instance reflect.Value) (interface{}, error) {
+	primaryColumns := tbl.getPrimaryColumns()
+	if len(primaryColumns) != 1 {
+		return nil, fmt.Errorf(invalidJoinPrimaryKeyErr, tbl.name)
+	}
 
-	for fieldName, fieldValue := range bpFieldValues {
-		switch value := fieldValue.(type) {
-		default:
-			fieldValues[fieldName] = value
-		case *sequenceValue, DynamicFieldValue, *AssociationFieldValue:
-			continue
-		}
+	return instance.Field(primaryColumns[0].originalModelIndex).Interface(), nil
+}
+
+// makeInstanceSliceFieldValue makes an empty slice value with the type of a slice field of the instance.
+func makeInstanceSliceFieldValue(instance reflect.Value, fieldName string, capacity int) (reflect.Value, bool) {
+	field, _ := structFieldByName(instance.Type(), fieldName)
+	_, isPtrElem := elemTypeOf(field.Type)
+
+	return reflect.MakeSlice(field.Type, 0, capacity), isPtrElem
+}
+
+// blueprintFieldValues holds the field values of the factory model instance in the order they are defined.
+type blueprintFieldValues struct {
+	fieldNames  []string
+	fieldValues map[string]interface{}
+}
+
+func newBlueprintFieldValues() *blueprintFieldValues {
+	return &blueprintFieldValues{
+		fieldValues: map[string]interface{}{},
 	}
+}
 
-	return fieldValues
+// set sets the value of a field.
+// A field which has been set before keeps its position in order.
+func (bpFieldValues *blueprintFieldValues) set(name string, value interface{}) {
+	if _, ok := bpFieldValues.fieldValues[name]; !ok {
+		bpFieldValues.fieldNames = append(bpFieldValues.fieldNames, name)
+	}
+	bpFieldValues.fieldValues[name] = value
 }
 
-func (bpFieldValues blueprintFieldValues) sequenceFieldValues() map[string]*sequenceValue {
-	fieldValues := map[string]*sequenceValue{}
+func (bpFieldValues *blueprintFieldValues) get(name string) (interface{}, bool) {
+	value, ok := bpFieldValues.fieldValues[name]
+	return value, ok
+}
 
-	for fieldName, fieldValue := range bpFieldValues {
-		if value, ok := fieldValue.(*sequenceValue); ok {
-			fieldValues[fieldName] = value
-		}
+func (bpFieldValues *blueprintFieldValues) remove(name string) {
+	if _, ok := bpFieldValues.fieldValues[name]; !ok {
+		return
 	}
 
-	return fieldValues
+	delete(bpFieldValues.fieldValues, name)
+	for i, fieldName := range bpFieldValues.fieldNames {
+		if fieldName == name {
+			bpFieldValues.fieldNames = append(bpFieldValues.fieldNames[:i], bpFieldValues.fieldNames[i+1:]...)
+			break
+		}
+	}
 }
 
-func (bpFieldValues blueprintFieldValues) dynamicFieldValues() map[string]DynamicFieldValue {
-	fieldValues := map[string]DynamicFieldValue{}
+// filter returns the field values passing the test function in order.
+func (bpFieldValues *blueprintFieldValues) filter(test func(value interface{}) bool) []*FieldDefinition {
+	var fields []*FieldDefinition
 
-	for fieldName, fieldValue := range bpFieldValues {
-		if value, ok := fieldValue.(DynamicFieldValue); ok {
-			fieldValues[fieldName] = value
+	for _, fieldName := range bpFieldValues.fieldNames {
+		fieldValue := bpFieldValues.fieldValues[fieldName]
+		if test(fieldValue) {
+			fields = append(fields, &FieldDefinition{Name: fieldName, Value: fieldValue})
 		}
 	}
 
-	return fieldValues
+	return fields
+}
+
+func (bpFieldValues *blueprintFieldValues) associationFieldValues() []*FieldDefinition {
+	return bpFieldValues.filter(func(value interface{}) bool {
+		_, ok := value.(*AssociationFieldValue)
+		return ok
+	})
+}
+
+func (bpFieldValues *blueprintFieldValues) manyToManyFieldValues() []*FieldDefinition {
+	return bpFieldValues.filter(func(value interface{}) bool {
+		_, ok := value.(*ManyToManyFieldValue)
+		return ok
+	})
+}
+
+func (bpFieldValues *blueprintFieldValues) filedValues() []*FieldDefinition {
+	return bpFieldValues.filter(func(value interface{}) bool {
+		switch value.(type) {
+		case *sequenceValue, DynamicFieldValue, DependentFieldValue, *AssociationFieldValue, *ManyToManyFieldValue:
+			return false
+		default:
+			return true
+		}
+	})
 }
 
-// makeBlueprintFieldValues create a new field value map of the factory model instance.
+func (bpFieldValues *blueprintFieldValues) sequenceFieldValues() []*FieldDefinition {
+	return bpFieldValues.filter(func(value interface{}) bool {
+		_, ok := value.(*sequenceValue)
+		return ok
+	})
+}
+
+// generatedFieldValues returns the dynamic field values and the dependent field values.
+func (bpFieldValues *blueprintFieldValues) generatedFieldValues() []*FieldDefinition {
+	return bpFieldValues.filter(func(value interface{}) bool {
+		switch value.(type) {
+		case DynamicFieldValue, DependentFieldValue:
+			return true
+		default:
+			return false
+		}
+	})
+}
+
+// makeBlueprintFieldValues create a new field value list of the factory model instance.
 // It chooses value for a model struct instance field as following:
-// 1. apply the Factory SequenceFiledValues
-// 2. apply the Factory FiledValues
-// 3. apply the Factory AssociationFieldValue
-// 4. apply the Factory DynamicFieldValues
-// 5. apply the Factory Traits
-// 6. apply the blueprint filedValues
-func makeBlueprintFieldValues(bp *blueprint) blueprintFieldValues {
-	bpFieldValues := blueprintFieldValues{}
-	setBlueprintFieldValuesInBlueprint(bp, bpFieldValues)
+// 1. apply the Factory Fields
+// 2. apply the Factory Traits Fields
+// 3. apply the blueprint filedValues, removing the fields skipped by WithoutField
+// 4. apply the blueprint associationOverrides
+// The fields are kept in the order they are defined in the Factory.
+// Fields which are only defined in traits or the blueprint follow them in the order they are applied.
+// It returns error if an association override can't be applied.
+func makeBlueprintFieldValues(bp *blueprint) (*blueprintFieldValues, error) {
+	bpFieldValues := newBlueprintFieldValues()
+	if err := setBlueprintFieldValuesInBlueprint(bp, bpFieldValues); err != nil {
+		return nil, err
+	}
 
-	return bpFieldValues
+	return bpFieldValues, nil
 }
 
-func setBlueprintFieldValuesInBlueprint(bp *blueprint, bpFieldValues blueprintFieldValues) {
+func setBlueprintFieldValuesInBlueprint(bp *blueprint, bpFieldValues *blueprintFieldValues) error {
 	// set field values in the Factory
 	setBlueprintFieldValuesInFactory(bp.factory, bpFieldValues)
 
@@ -350,34 +884,86 @@ func setBlueprintFieldValuesInBlueprint(bp *blueprint, bpFieldValues blueprintFi
 	setBlueprintFieldValuesInFactoryTraits(bp.factory, bp.traits, bpFieldValues)
 
 	// set field values in the blueprint filedValues
-	for fieldName, fieldValue := range bp.filedValues {
-		bpFieldValues[fieldName] = fieldValue
+	for _, field := range bp.filedValues {
+		if _, ok := field.Value.(skippedFieldValue); ok {
+			bpFieldValues.remove(field.Name)
+			continue
+		}
+		bpFieldValues.set(field.Name, field.Value)
 	}
+
+	// set association values in the blueprint associationOverrides
+	for _, fieldName := range sortedFieldNames(bp.associationOverrides) {
+		if err := setBlueprintFieldValuesInAssociationOverride(bp.factory, fieldName, bp.associationOverrides[fieldName], bpFieldValues); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func setBlueprintFieldValuesInFactory(f *Factory, bpFieldValues blueprintFieldValues) {
-	// set field values in SequenceFiledValues
-	for fieldName, sequenceValue := range f.SequenceFiledValues {
-		bpFieldValues[fieldName] = sequenceValue
+func setBlueprintFieldValuesInAssociationOverride(f *Factory, fieldName string, override *associationOverride, bpFieldValues *blueprintFieldValues) error {
+	fieldValue, _ := bpFieldValues.get(fieldName)
+	associationFieldValue, ok := fieldValue.(*AssociationFieldValue)
+	if !ok {
+		return nil
 	}
 
-	// set field values in FiledValues
-	for fieldName, fieldValue := range f.FiledValues {
-		bpFieldValues[fieldName] = fieldValue
+	// generate no association, and leave the reference field zero
+	if override.skipped {
+		bpFieldValues.remove(fieldName)
+		bpFieldValues.remove(associationFieldValue.ReferenceField)
+		if associationFieldValue.TypeField != "" {
+			bpFieldValues.remove(associationFieldValue.TypeField)
+		}
+		return nil
+	}
+
+	if override.factory != nil {
+		associationFieldValue = associationFieldValue.withOriginalFactory(override.factory)
+	}
+	if override.strategy != "" {
+		associationFieldValue = associationFieldValue.withStrategy(override.strategy)
+	}
+	if len(override.options) > 0 {
+		associationFieldValue = associationFieldValue.withOptions(override.options)
 	}
 
-	// set filed values in AssociationFieldValue
-	for fieldName, associationFieldValue := range f.AssociationFieldValues {
-		bpFieldValues[fieldName] = associationFieldValue
+	// reuse an existing association instance instead of generating it
+	if override.instance != nil {
+		bpFieldValues.set(fieldName, override.instance)
+		bpFieldValues.set(associationFieldValue.ReferenceField, override.id)
+		if associationFieldValue.TypeField != "" {
+			associationType := reflect.Indirect(reflect.ValueOf(override.instance)).Type()
+			bpFieldValues.set(associationFieldValue.TypeField, associationFieldValue.typeValue(f.ModelType, associationType))
+		}
+		return nil
+	}
+
+	// refer to an existing association by id instead of generating it
+	if override.hasID {
+		bpFieldValues.remove(fieldName)
+		bpFieldValues.set(associationFieldValue.ReferenceField, override.id)
+		if associationFieldValue.TypeField != "" {
+			// the type of polymorphic association is decided by the factory
+			if associationFieldValue.OriginalFactory == nil {
+				return fmt.Errorf(undefinedAssociationFactoryErr, fieldName)
+			}
+			bpFieldValues.set(associationFieldValue.TypeField, associationFieldValue.typeValue(f.ModelType, associationFieldValue.OriginalFactory.ModelType))
+		}
+		return nil
 	}
 
-	// set field values in DynamicFieldValues
-	for fieldName, dynamicFieldValue := range f.DynamicFieldValues {
-		bpFieldValues[fieldName] = dynamicFieldValue
+	bpFieldValues.set(fieldName, associationFieldValue)
+	return nil
+}
+
+func setBlueprintFieldValuesInFactory(f *Factory, bpFieldValues *blueprintFieldValues) {
+	for _, field := range f.Fields {
+		bpFieldValues.set(field.Name, field.Value)
 	}
 }
 
-func setBlueprintFieldValuesInFactoryTraits(f *Factory, traits []string, bpFieldValues blueprintFieldValues) {
+func setBlueprintFieldValuesInFactoryTraits(f *Factory, traits []string, bpFieldValues *blueprintFieldValues) {
 	for _, trait := range traits {
 		traitFactory := f.Traits[trait]
 		setBlueprintFieldValuesInFactory(traitFactory, bpFieldValues)
@@ -387,7 +973,7 @@ func setBlueprintFieldValuesInFactoryTraits(f *Factory, traits []string, bpField
 func newDefaultBlueprintFromAssociationFieldValue(fieldValue *AssociationFieldValue) *blueprint {
 	return &blueprint{
 		factory:     fieldValue.OriginalFactory,
-		filedValues: fieldValue.Factory.FiledValues,
+		filedValues: append([]*FieldDefinition{}, fieldValue.Factory.Fields...),
 	}
 }
 
@@ -398,9 +984,55 @@ func newBlueprintFromAssociationFieldValueForCreateAndDelete(fieldValue *Associa
 	return bp
 }
 
-func executeCallbacks(modelInstancePtrIface interface{}, callbacks []Callback) error {
+func newDefaultBlueprintFromManyToManyFieldValue(fieldValue *ManyToManyFieldValue) *blueprint {
+	return &blueprint{
+		factory:     fieldValue.OriginalFactory,
+		filedValues: append([]*FieldDefinition{}, fieldValue.Factory.Fields...),
+	}
+}
+
+func newBlueprintFromManyToManyFieldValueForCreate(fieldValue *ManyToManyFieldValue) *blueprint {
+	bp := newDefaultBlueprintFromManyToManyFieldValue(fieldValue)
+	bp.table = newTable(fieldValue.OriginalFactory)
+
+	return bp
+}
+
+func (bp *blueprint) executeBeforeDeleteCallbacks(modelInstance reflect.Value, e *Evaluator) error {
+	ptrIface := modelInstance.Addr().Interface()
+
+	// execute trait before delete callbacks in reverse order of traits
+	for i := len(bp.traits) - 1; i >= 0; i-- {
+		trait := bp.traits[i]
+		traitFactory := bp.factory.Traits[trait]
+		if err := executeCallbacks(ptrIface, e, traitFactory.BeforeDeleteCallbacks); err != nil {
+			return err
+		}
+	}
+
+	// execute before delete callbacks in bp.facotry
+	return executeCallbacks(ptrIface, e, bp.factory.BeforeDeleteCallbacks)
+}
+
+func (bp *blueprint) executeAfterDeleteCallbacks(modelInstance reflect.Value, e *Evaluator) error {
+	ptrIface := modelInstance.Addr().Interface()
+
+	// execute trait after delete callbacks in reverse order of traits
+	for i := len(bp.traits) - 1; i >= 0; i-- {
+		trait := bp.traits[i]
+		traitFactory := bp.factory.Traits[trait]
+		if err := executeCallbacks(ptrIface, e, traitFactory.AfterDeleteCallbacks); err != nil {
+			return err
+		}
+	}
+
+	// execute after delete callbacks in bp.facotry
+	return executeCallbacks(ptrIface, e, bp.factory.AfterDeleteCallbacks)
+}
+
+func executeCallbacks(modelInstancePtrIface interface{}, e *Evaluator, callbacks []EvaluatorCallback) error {
 	for _, callback := range callbacks {
-		err := callback(modelInstancePtrIface)
+		err := callback(modelInstancePtrIface, e)
 		if err != nil {
 			return err
 		}
@@ -412,7 +1044,52 @@ func chainedFieldNameToFieldNames(name string) []string {
 	return strings.Split(name, ".")
 }
 
-func setInstanceFieldValue(instance reflect.Value, fieldName string, fieldValue interface{}) {
+// sortedFieldNames returns the keys of a field value map in order.
+func sortedFieldNames(fieldValues interface{}) []string {
+	keys := reflect.ValueOf(fieldValues).MapKeys()
+
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		names[i] = key.String()
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// instanceFieldValue returns the value of a field of the instance.
+// It returns the zero value of the field if any struct pointer in the chained field name is nil.
+func instanceFieldValue(instance reflect.Value, fieldName string) interface{} {
+	var field = instance
+	fieldNames := chainedFieldNameToFieldNames(fieldName)
+
+	for _, name := range fieldNames {
+		if field.Kind() == reflect.Ptr {
+			if field.IsNil() {
+				structField, _ := structFieldByName(instance.Type(), fieldName)
+				return reflect.Zero(structField.Type).Interface()
+			}
+			field = field.Elem()
+		}
+		field = field.FieldByName(name)
+	}
+
+	return field.Interface()
+}
+
+// setInstanceGeneratedFieldValue converts the value generated by sequences or generators to the type of the field,
+// and sets it into the field. It returns error if the value can't be converted.
+func setInstanceGeneratedFieldValue(instance reflect.Value, fieldName string, fieldValue interface{}) error {
+	field, _ := structFieldByName(instance.Type(), fieldName)
+	value, err := utils.ConvertValue(fieldValue, field.Type)
+	if err != nil {
+		return fmt.Errorf(invalidGeneratedValueErr, fieldName, err)
+	}
+
+	return setInstanceFieldValue(instance, fieldName, value)
+}
+
+func setInstanceFieldValue(instance reflect.Value, fieldName string, fieldValue interface{}) error {
 	var field reflect.Value
 	var structValue = instance
 	fieldNames := chainedFieldNameToFieldNames(fieldName)
@@ -442,5 +1119,21 @@ func setInstanceFieldValue(instance reflect.Value, fieldName string, fieldValue
 		}
 	}
 
-	field.Set(reflect.ValueOf(fieldValue))
+	if fieldValue == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+
+	value := reflect.ValueOf(fieldValue)
+	if !value.Type().AssignableTo(field.Type()) {
+		// the values checked by def.Field and WithField are converted for each instance,
+		// so that every instance gets its own pointer of an auto-addressed value
+		converted, err := utils.ConvertValue(fieldValue, field.Type())
+		if err != nil {
+			return fmt.Errorf(invalidFieldValueErr, fieldName, err)
+		}
+		value = reflect.ValueOf(converted)
+	}
+	field.Set(value)
+	return nil
 }
@@ -95,4 +95,74 @@ func TestSetInstanceFieldValue(t *testing.T) {
 	if tt.E.b != 0 {
 		t.Errorf("setInstanceFieldValue failed")
 	}
+
+	// test unconvertible value
+
+	tt = &test{}
+	v = reflect.ValueOf(tt)
+
+	if err := setInstanceFieldValue(v, "A", 1); err == nil {
+		t.Errorf("setInstanceFieldValue should return error when the value can't be converted")
+	}
+}
+
+func TestBlueprintElement(t *testing.T) {
+	type testModel struct {
+		ID   int64
+		Name string
+	}
+
+	f := &Factory{
+		ModelType: reflect.TypeOf(testModel{}),
+		Fields: []*FieldDefinition{
+			{Name: "ID", Value: newSequenceValue(1, func(n int64) (interface{}, error) {
+				return n, nil
+			})},
+		},
+		Traits: map[string]*Factory{},
+	}
+	bp := newDefaultBlueprint(f)
+	if err := applyFactoryOptions(bp, []factoryOption{
+		WithSequenceStart("ID", 10),
+		WithIndexedField("Name", func(i int) interface{} {
+			return "name"
+		}),
+	}); err != nil {
+		t.Fatalf("applyFactoryOptions failed with error: %v", err)
+	}
+
+	// element doesn't modify the blueprint
+	elem, err := bp.element(0)
+	if err != nil {
+		t.Fatalf("element failed with error: %v", err)
+	}
+	if _, err := elem.build(); err != nil {
+		t.Fatalf("build failed with error: %v", err)
+	}
+	if n := bp.sequenceStarts["ID"]; n != 10 || len(bp.filedValues) != 0 {
+		t.Errorf("element modified the blueprint with sequence start=%d, %d field values", n, len(bp.filedValues))
+	}
+
+	// generateElement counts the numbers from WithSequenceStart by the index
+	for i, want := range []int64{10, 11} {
+		instance, err := bp.generateElement(i, (*blueprint).build)
+		if err != nil {
+			t.Fatalf("generateElement failed with error: %v", err)
+		}
+		if model := instance.(*testModel); model.ID != want || model.Name != "name" {
+			t.Errorf("generateElement failed with model=%+v, want ID=%d, Name=name", model, want)
+		}
+	}
+	if n := bp.sequenceStarts["ID"]; n != 10 {
+		t.Errorf("generateElement modified the blueprint with sequence start=%d, want 10", n)
+	}
+
+	// generating again starts from WithSequenceStart again
+	instance, err := bp.generateElement(0, (*blueprint).build)
+	if err != nil {
+		t.Fatalf("generateElement failed with error: %v", err)
+	}
+	if model := instance.(*testModel); model.ID != 10 {
+		t.Errorf("generateElement again failed with ID=%d, want ID=10", model.ID)
+	}
 }
@@ -1,6 +1,20 @@
 package factory
 
-import "database/sql"
+import (
+	"context"
+	"database/sql"
+)
+
+// Executor is the interface that executes SQL statements.
+// Both *sql.DB and *sql.Tx implement it.
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+const (
+	undefinedDBErr = "database connection isn't set, call SetDB or use WithExecutor"
+)
 
 var dbConnection *sql.DB
 
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/nauyey/factory"
+	"github.com/nauyey/factory/utils"
 )
 
 // def supports the following features:
@@ -25,8 +26,24 @@ const (
 	invalidFieldValueTypeErr    = "cannot use value (type %v) as type %v of field %s to define factory of %s"
 	nestedAssociationErr        = "association %s error: nested associations isn't allowed"
 	nestedTraitErr              = "Trait %s error: nested traits is not allowed"
+	duplicateTraitErr           = "duplicate definition of Trait %s"
 	callbackInAssociationErr    = "%s is not allowed in Associations"
 	duplicateFieldDefinitionErr = "duplicate definition of field %s"
+	invalidManyToManyFieldErr   = "cannot use field %s (type %v) as many-to-many association of %s"
+	invalidTypeFieldErr         = "cannot use field %s (type %v) as type field of polymorphic association %s"
+	undefinedPolymorphicErr     = "undefined polymorphic association %s"
+	undefinedDefaultFactoryErr  = "polymorphic association %s error: overriding fields needs a default factory"
+	strategyOutOfAssociationErr = "Strategy %s is only allowed in Associations"
+	invalidStrategyErr          = "invalid strategy %s"
+	strategyInManyToManyErr     = "many-to-many association %s error: Strategy is not allowed"
+	invalidManyToManyCountErr   = "many-to-many association %s error: invalid count %d, want a non-negative integer"
+	transientFieldConflictErr   = "transient field %s conflicts with the field of %s"
+	duplicateTransientErr       = "duplicate definition of transient field %s"
+	transientInAssociationErr   = "transient field %s is not allowed in Associations"
+	invalidDBSequenceFieldErr   = "cannot use field %s (type %v) as sequence field seeded from database"
+	associationDefinitionErr    = "association %s error: %w"
+	nilAssociationFactoryErr    = "association %s error: factory is nil"
+	traitDefinitionErr          = "Trait %s error: %w"
 )
 
 func newDefaultFactory(model interface{}, table string) *factory.Factory {
@@ -36,12 +53,10 @@ func newDefaultFactory(model interface{}, table string) *factory.Factory {
 	}
 
 	return &factory.Factory{
-		ModelType:              modelType,
-		Table:                  table,
-		FiledValues:            map[string]interface{}{},
-		DynamicFieldValues:     map[string]factory.DynamicFieldValue{},
-		AssociationFieldValues: map[string]*factory.AssociationFieldValue{},
-		Traits:                 map[string]*factory.Factory{},
+		ModelType:  modelType,
+		Table:      table,
+		Transients: map[string]interface{}{},
+		Traits:     map[string]*factory.Factory{},
 
 		CanHaveAssociations: true,
 		CanHaveTraits:       true,
@@ -51,10 +66,8 @@ func newDefaultFactory(model interface{}, table string) *factory.Factory {
 
 func newDefaultFactoryForTrait(f *factory.Factory) *factory.Factory {
 	return &factory.Factory{
-		ModelType:              f.ModelType,
-		FiledValues:            map[string]interface{}{},
-		DynamicFieldValues:     map[string]factory.DynamicFieldValue{},
-		AssociationFieldValues: map[string]*factory.AssociationFieldValue{},
+		ModelType:  f.ModelType,
+		Transients: map[string]interface{}{},
 
 		CanHaveAssociations: true,
 		CanHaveTraits:       false,
@@ -64,9 +77,7 @@ func newDefaultFactoryForTrait(f *factory.Factory) *factory.Factory {
 
 func newDefaultFactoryForAssociation(f *factory.Factory) *factory.Factory {
 	return &factory.Factory{
-		ModelType:          f.ModelType,
-		FiledValues:        map[string]interface{}{},
-		DynamicFieldValues: map[string]factory.DynamicFieldValue{},
+		ModelType: f.ModelType,
 
 		CanHaveAssociations: false,
 		CanHaveTraits:       false,
@@ -76,23 +87,31 @@ func newDefaultFactoryForAssociation(f *factory.Factory) *factory.Factory {
 
 type definitionOption func(*factory.Factory) error
 
-// Field defines the value of a field in the factory
+// Option is the type of the options passed to NewFactory, like Field and Trait.
+// It allows other packages to wrap NewFactory.
+type Option = definitionOption
+
+// Field defines the value of a field in the factory.
+// The value is accepted if it's assignable or safely convertible to the type of the field,
+// like 30 for an int32 field, nil for a pointer field, and "name" for a *string field.
 func Field(name string, value interface{}) definitionOption {
 	return func(f *factory.Factory) error {
 		if ok := fieldExists(f.ModelType, name); !ok {
 			return fmt.Errorf(invalidFieldNameErr, name, f.ModelType.Name())
 		}
 
+		// the value is converted when it's set to each instance,
+		// so that auto-addressed pointers aren't shared by instances
 		field, _ := structFieldByName(f.ModelType, name)
-		if valueType := reflect.TypeOf(value); valueType != field.Type {
-			return fmt.Errorf(invalidFieldValueTypeErr, valueType, field.Type, name, f.ModelType.Name())
+		if _, err := utils.ConvertValue(value, field.Type); err != nil {
+			return fmt.Errorf(invalidFieldValueTypeErr, reflect.TypeOf(value), field.Type, name, f.ModelType.Name())
 		}
 
 		if ok := definedField(f, name); ok {
 			return fmt.Errorf(duplicateFieldDefinitionErr, name)
 		}
 
-		f.FiledValues[name] = value
+		f.AddFieldValue(name, value)
 		return nil
 	}
 }
@@ -114,7 +133,90 @@ func SequenceField(name string, first int64, value factory.SequenceFieldValue) d
 	}
 }
 
+// SequenceFromDB defines an integer sequence field in the factory, whose values start above the current maximum of column in database.
+// The maximum is queried from the factory's table when the factory is created for the first time,
+// so the values won't conflict with the existing rows.
+// When the factory is only built, the sequence starts from 1.
+// Usage example:
+//
+// UserFactory := NewFactory(User{}, "user_table",
+// 	SequenceFromDB("ID", "id"),
+// )
+//
+func SequenceFromDB(name string, column string) definitionOption {
+	return func(f *factory.Factory) error {
+		if ok := fieldExists(f.ModelType, name); !ok {
+			return fmt.Errorf(invalidFieldNameErr, name, f.ModelType.Name())
+		}
+
+		if ok := definedField(f, name); ok {
+			return fmt.Errorf(duplicateFieldDefinitionErr, name)
+		}
+
+		field, _ := structFieldByName(f.ModelType, name)
+		switch field.Type.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		default:
+			return fmt.Errorf(invalidDBSequenceFieldErr, name, field.Type)
+		}
+
+		f.AddDBSequenceFiledValue(name, column)
+		return nil
+	}
+}
+
+// NewSequence defines a named sequence, which can be referenced by SequenceRef from any factories.
+// It returns the name of the sequence. It panics if a sequence with the same name has been defined.
+// Usage example:
+//
+// var emailSequence = NewSequence("email", 1, func(n int64) (interface{}, error) {
+// 	return fmt.Sprintf("person%d@example.com", n), nil
+// })
+//
+// UserFactory := NewFactory(User{}, "user_table",
+// 	SequenceRef("Email", emailSequence),
+// )
+//
+func NewSequence(name string, first int64, value factory.SequenceFieldValue) string {
+	return NewSequenceIn(factory.DefaultRegistry, name, first, value)
+}
+
+// NewSequenceIn defines a named sequence in registry r, which can be referenced by SequenceRefIn.
+// It returns the name of the sequence. It panics if a sequence with the same name has been defined in r.
+func NewSequenceIn(r *factory.Registry, name string, first int64, value factory.SequenceFieldValue) string {
+	if err := r.DefineSequence(name, first, value); err != nil {
+		panic(err)
+	}
+
+	return name
+}
+
+// SequenceRef defines the value of a sequence field in the factory by a named sequence defined by NewSequence.
+// The named sequence can be shared by fields of different factories, and each value of it is used only once.
+func SequenceRef(name string, sequenceName string) definitionOption {
+	return SequenceRefIn(factory.DefaultRegistry, name, sequenceName)
+}
+
+// SequenceRefIn defines the value of a sequence field in the factory by a named sequence defined in registry r.
+func SequenceRefIn(r *factory.Registry, name string, sequenceName string) definitionOption {
+	return func(f *factory.Factory) error {
+		if ok := fieldExists(f.ModelType, name); !ok {
+			return fmt.Errorf(invalidFieldNameErr, name, f.ModelType.Name())
+		}
+
+		if ok := definedField(f, name); ok {
+			return fmt.Errorf(duplicateFieldDefinitionErr, name)
+		}
+
+		return f.AddSequenceRefFieldValueIn(r, name, sequenceName)
+	}
+}
+
 // DynamicField defines the value generator of a dynamic field in the factory.
+// The generators are called in the order they are defined, after static fields and sequence fields are set.
+// So the generator only sees the generated fields defined before it. Use DependentField to read the others.
+// factory.Lint reports the generators reading the generated fields which haven't been evaluated.
 func DynamicField(name string, value factory.DynamicFieldValue) definitionOption {
 	return func(f *factory.Factory) error {
 		if ok := fieldExists(f.ModelType, name); !ok {
@@ -125,7 +227,47 @@ func DynamicField(name string, value factory.DynamicFieldValue) definitionOption
 			return fmt.Errorf(duplicateFieldDefinitionErr, name)
 		}
 
-		f.DynamicFieldValues[name] = value
+		f.AddFieldValue(name, value)
+		return nil
+	}
+}
+
+// DependentField defines the value generator of a field which depends on transient fields or other fields in the factory.
+// They can be read by the Evaluator passed to the generator.
+// The fields read by Evaluator.Field are always evaluated before the dependent field.
+func DependentField(name string, value factory.DependentFieldValue) definitionOption {
+	return func(f *factory.Factory) error {
+		if ok := fieldExists(f.ModelType, name); !ok {
+			return fmt.Errorf(invalidFieldNameErr, name, f.ModelType.Name())
+		}
+
+		if ok := definedField(f, name); ok {
+			return fmt.Errorf(duplicateFieldDefinitionErr, name)
+		}
+
+		f.AddFieldValue(name, value)
+		return nil
+	}
+}
+
+// Transient defines a transient field with its default value.
+// Transient fields aren't model struct fields. They can be overridden by factory.WithTransient,
+// and be read by the Evaluator in DependentField generators and callbacks with evaluator.
+func Transient(name string, value interface{}) definitionOption {
+	return func(f *factory.Factory) error {
+		if !f.CanHaveCallbacks {
+			return fmt.Errorf(transientInAssociationErr, name)
+		}
+
+		if ok := fieldExists(f.ModelType, name); ok {
+			return fmt.Errorf(transientFieldConflictErr, name, f.ModelType.Name())
+		}
+
+		if _, ok := f.Transients[name]; ok {
+			return fmt.Errorf(duplicateTransientErr, name)
+		}
+
+		f.Transients[name] = value
 		return nil
 	}
 }
@@ -150,23 +292,188 @@ func Association(name, referenceField, associationReferenceField string, origina
 			Factory:                   newDefaultFactoryForAssociation(originalFactory),
 		}
 
-		for _, opt := range opts {
-			err := opt(associationFieldValue.Factory)
-			if err != nil {
-				return err
-			}
+		var errs Errors
+		if err := applyDefinitionOptions(associationFieldValue.Factory, opts); err != nil {
+			errs = wrap(err, associationDefinitionErr, name)
 		}
 
 		if ok := definedField(f, name); ok {
-			return fmt.Errorf(duplicateFieldDefinitionErr, name)
+			errs = errs.append(fmt.Errorf(duplicateFieldDefinitionErr, name))
+		}
+		if len(errs) > 0 {
+			return errs
 		}
 
-		f.AssociationFieldValues[name] = associationFieldValue
+		f.AddFieldValue(name, associationFieldValue)
 
 		return nil
 	}
 }
 
+// PolymorphicAssociation defines the value of a polymorphic association field,
+// which may be generated from factories of different model types.
+// Which factory is used can be chosen by WithAssociation when the model instance is generated,
+// otherwise the default factory originalFactory will be used. originalFactory can be nil.
+// The typeField will be set with the model struct name of the chosen factory, which can be customized by PolymorphicType,
+// and the referenceField will be set with the associationReferenceField of the association instance.
+func PolymorphicAssociation(name, typeField, referenceField, associationReferenceField string, originalFactory *factory.Factory, opts ...definitionOption) definitionOption {
+	return func(f *factory.Factory) error {
+		if ok := fieldExists(f.ModelType, name); !ok {
+			return fmt.Errorf(invalidFieldNameErr, name, f.ModelType.Name())
+		}
+		if ok := fieldExists(f.ModelType, typeField); !ok {
+			return fmt.Errorf(invalidFieldNameErr, typeField, f.ModelType.Name())
+		}
+
+		field, _ := structFieldByName(f.ModelType, typeField)
+		if field.Type.Kind() != reflect.String {
+			return fmt.Errorf(invalidTypeFieldErr, typeField, field.Type, name)
+		}
+
+		if !f.CanHaveAssociations {
+			return fmt.Errorf(nestedAssociationErr, name)
+		}
+
+		associationFieldValue := &factory.AssociationFieldValue{
+			ReferenceField:            referenceField,
+			AssociationReferenceField: associationReferenceField,
+			TypeField:                 typeField,
+			OriginalFactory:           originalFactory,
+			Factory:                   &factory.Factory{},
+		}
+
+		if originalFactory != nil {
+			associationFieldValue.Factory = newDefaultFactoryForAssociation(originalFactory)
+		} else if len(opts) > 0 {
+			return fmt.Errorf(undefinedDefaultFactoryErr, name)
+		}
+
+		var errs Errors
+		if err := applyDefinitionOptions(associationFieldValue.Factory, opts); err != nil {
+			errs = wrap(err, associationDefinitionErr, name)
+		}
+
+		if ok := definedField(f, name); ok {
+			errs = errs.append(fmt.Errorf(duplicateFieldDefinitionErr, name))
+		}
+		if len(errs) > 0 {
+			return errs
+		}
+
+		f.AddFieldValue(name, associationFieldValue)
+
+		return nil
+	}
+}
+
+// PolymorphicType sets the value of the type field of polymorphic association name,
+// when the associated object is generated from associationFactory, or any factory of the same model type.
+// By default, the type field is set with the model struct name.
+// It must be used after the PolymorphicAssociation in the same factory definition.
+// Usage example:
+//
+// CommentFactory := NewFactory(Comment{}, "comment_table",
+// 	PolymorphicAssociation("Commentable", "CommentableType", "CommentableID", "ID", BlogFactory),
+// 	PolymorphicType("Commentable", BlogFactory, "blogs"),
+// )
+//
+func PolymorphicType(name string, associationFactory *factory.Factory, value string) definitionOption {
+	return func(f *factory.Factory) error {
+		fieldValue, _ := f.FieldValue(name)
+		associationFieldValue, ok := fieldValue.(*factory.AssociationFieldValue)
+		if !ok || associationFieldValue.TypeField == "" {
+			return fmt.Errorf(undefinedPolymorphicErr, name)
+		}
+		if associationFactory == nil {
+			return fmt.Errorf(nilAssociationFactoryErr, name)
+		}
+
+		if associationFieldValue.TypeValues == nil {
+			associationFieldValue.TypeValues = map[reflect.Type]string{}
+		}
+		associationFieldValue.TypeValues[associationFactory.ModelType] = value
+		return nil
+	}
+}
+
+// ManyToMany defines the value of a many-to-many association field.
+// The field must be a slice of the originalFactory model type (or pointer of it).
+// count instances will be generated from originalFactory for the field.
+// When the model struct is created, each of them will be linked to it by inserting a row
+// into joinTable, with foreignKey referencing the primary key of the model struct
+// and associationForeignKey referencing the primary key of the associated instance.
+func ManyToMany(name string, originalFactory *factory.Factory, joinTable, foreignKey, associationForeignKey string, count int, opts ...definitionOption) definitionOption {
+	return func(f *factory.Factory) error {
+		if ok := fieldExists(f.ModelType, name); !ok {
+			return fmt.Errorf(invalidFieldNameErr, name, f.ModelType.Name())
+		}
+
+		if originalFactory == nil {
+			return fmt.Errorf(nilAssociationFactoryErr, name)
+		}
+
+		field, _ := structFieldByName(f.ModelType, name)
+		if !isSliceOf(field.Type, originalFactory.ModelType) {
+			return fmt.Errorf(invalidManyToManyFieldErr, name, field.Type, originalFactory.ModelType.Name())
+		}
+
+		if !f.CanHaveAssociations {
+			return fmt.Errorf(nestedAssociationErr, name)
+		}
+
+		manyToManyFieldValue := &factory.ManyToManyFieldValue{
+			JoinTable:             joinTable,
+			ForeignKey:            foreignKey,
+			AssociationForeignKey: associationForeignKey,
+			Count:                 count,
+			OriginalFactory:       originalFactory,
+			Factory:               newDefaultFactoryForAssociation(originalFactory),
+		}
+
+		var errs Errors
+		if err := applyDefinitionOptions(manyToManyFieldValue.Factory, opts); err != nil {
+			errs = wrap(err, associationDefinitionErr, name)
+		}
+
+		if manyToManyFieldValue.Factory.Strategy != "" {
+			errs = errs.append(fmt.Errorf(strategyInManyToManyErr, name))
+		}
+		if count < 0 {
+			errs = errs.append(fmt.Errorf(invalidManyToManyCountErr, name, count))
+		}
+
+		if ok := definedField(f, name); ok {
+			errs = errs.append(fmt.Errorf(duplicateFieldDefinitionErr, name))
+		}
+		if len(errs) > 0 {
+			return errs
+		}
+
+		f.AddFieldValue(name, manyToManyFieldValue)
+
+		return nil
+	}
+}
+
+// Strategy sets the strategy to generate an association.
+// It can only be used in Association and PolymorphicAssociation.
+// For example, with factory.StrategyBuild, the association will only be built
+// even if the parent model instance is created.
+func Strategy(strategy string) definitionOption {
+	return func(f *factory.Factory) error {
+		if f.CanHaveAssociations {
+			return fmt.Errorf(strategyOutOfAssociationErr, strategy)
+		}
+
+		if strategy != factory.StrategyBuild && strategy != factory.StrategyCreate {
+			return fmt.Errorf(invalidStrategyErr, strategy)
+		}
+
+		f.Strategy = strategy
+		return nil
+	}
+}
+
 // Trait allows you to group fields together and then apply them to any factory.
 func Trait(traitName string, opts ...definitionOption) definitionOption {
 	return func(f *factory.Factory) error {
@@ -176,11 +483,16 @@ func Trait(traitName string, opts ...definitionOption) definitionOption {
 
 		traitFactory := newDefaultFactoryForTrait(f)
 
-		for _, opt := range opts {
-			err := opt(traitFactory)
-			if err != nil {
-				return err
-			}
+		var errs Errors
+		if err := applyDefinitionOptions(traitFactory, opts); err != nil {
+			errs = wrap(err, traitDefinitionErr, traitName)
+		}
+
+		if _, ok := f.Traits[traitName]; ok {
+			errs = errs.append(fmt.Errorf(duplicateTraitErr, traitName))
+		}
+		if len(errs) > 0 {
+			return errs
 		}
 
 		f.Traits[traitName] = traitFactory
@@ -189,6 +501,33 @@ func Trait(traitName string, opts ...definitionOption) definitionOption {
 	}
 }
 
+// BeforeBuild sets callback called before the fields of the model struct been set, when it's built or created.
+func BeforeBuild(callback factory.Callback) definitionOption {
+	return func(f *factory.Factory) error {
+		if !f.CanHaveCallbacks {
+			return fmt.Errorf(callbackInAssociationErr, "BeforeBuild")
+		}
+
+		f.BeforeBuildCallbacks = append(f.BeforeBuildCallbacks, func(model interface{}, e *factory.Evaluator) error {
+			return callback(model)
+		})
+		return nil
+	}
+}
+
+// BeforeBuildWithEvaluator sets callback called before the fields of the model struct been set, when it's built or created.
+// The callback can access transient fields and the traits in use by the Evaluator.
+func BeforeBuildWithEvaluator(callback factory.EvaluatorCallback) definitionOption {
+	return func(f *factory.Factory) error {
+		if !f.CanHaveCallbacks {
+			return fmt.Errorf(callbackInAssociationErr, "BeforeBuildWithEvaluator")
+		}
+
+		f.BeforeBuildCallbacks = append(f.BeforeBuildCallbacks, callback)
+		return nil
+	}
+}
+
 // AfterBuild sets callback called after the model struct been build.
 // REMIND that AfterBuild callback will be called not only when Build a model struct
 // but also when Create a model struct. Because to Create a model struct instance,
@@ -199,6 +538,21 @@ func AfterBuild(callback factory.Callback) definitionOption {
 			return fmt.Errorf(callbackInAssociationErr, "AfterBuild")
 		}
 
+		f.AfterBuildCallbacks = append(f.AfterBuildCallbacks, func(model interface{}, e *factory.Evaluator) error {
+			return callback(model)
+		})
+		return nil
+	}
+}
+
+// AfterBuildWithEvaluator sets callback called after the model struct been build.
+// The callback can access transient fields by the Evaluator.
+func AfterBuildWithEvaluator(callback factory.EvaluatorCallback) definitionOption {
+	return func(f *factory.Factory) error {
+		if !f.CanHaveCallbacks {
+			return fmt.Errorf(callbackInAssociationErr, "AfterBuildWithEvaluator")
+		}
+
 		f.AfterBuildCallbacks = append(f.AfterBuildCallbacks, callback)
 		return nil
 	}
@@ -211,6 +565,21 @@ func BeforeCreate(callback factory.Callback) definitionOption {
 			return fmt.Errorf(callbackInAssociationErr, "BeforeCreate")
 		}
 
+		f.BeforeCreateCallbacks = append(f.BeforeCreateCallbacks, func(model interface{}, e *factory.Evaluator) error {
+			return callback(model)
+		})
+		return nil
+	}
+}
+
+// BeforeCreateWithEvaluator sets callback called before the model struct been saved.
+// The callback can access transient fields by the Evaluator.
+func BeforeCreateWithEvaluator(callback factory.EvaluatorCallback) definitionOption {
+	return func(f *factory.Factory) error {
+		if !f.CanHaveCallbacks {
+			return fmt.Errorf(callbackInAssociationErr, "BeforeCreateWithEvaluator")
+		}
+
 		f.BeforeCreateCallbacks = append(f.BeforeCreateCallbacks, callback)
 		return nil
 	}
@@ -223,11 +592,107 @@ func AfterCreate(callback factory.Callback) definitionOption {
 			return fmt.Errorf(callbackInAssociationErr, "AfterCreate")
 		}
 
+		f.AfterCreateCallbacks = append(f.AfterCreateCallbacks, func(model interface{}, e *factory.Evaluator) error {
+			return callback(model)
+		})
+		return nil
+	}
+}
+
+// AfterCreateWithEvaluator sets callback called after the model struct been saved.
+// The callback can access transient fields by the Evaluator.
+func AfterCreateWithEvaluator(callback factory.EvaluatorCallback) definitionOption {
+	return func(f *factory.Factory) error {
+		if !f.CanHaveCallbacks {
+			return fmt.Errorf(callbackInAssociationErr, "AfterCreateWithEvaluator")
+		}
+
 		f.AfterCreateCallbacks = append(f.AfterCreateCallbacks, callback)
 		return nil
 	}
 }
 
+// AfterStub sets callback called after the model struct been stubbed by factory.BuildStubbed.
+func AfterStub(callback factory.Callback) definitionOption {
+	return func(f *factory.Factory) error {
+		if !f.CanHaveCallbacks {
+			return fmt.Errorf(callbackInAssociationErr, "AfterStub")
+		}
+
+		f.AfterStubCallbacks = append(f.AfterStubCallbacks, func(model interface{}, e *factory.Evaluator) error {
+			return callback(model)
+		})
+		return nil
+	}
+}
+
+// AfterStubWithEvaluator sets callback called after the model struct been stubbed by factory.BuildStubbed.
+// The callback can access transient fields by the Evaluator.
+func AfterStubWithEvaluator(callback factory.EvaluatorCallback) definitionOption {
+	return func(f *factory.Factory) error {
+		if !f.CanHaveCallbacks {
+			return fmt.Errorf(callbackInAssociationErr, "AfterStubWithEvaluator")
+		}
+
+		f.AfterStubCallbacks = append(f.AfterStubCallbacks, callback)
+		return nil
+	}
+}
+
+// BeforeDelete sets callback called before the model struct been deleted by factory.Delete.
+func BeforeDelete(callback factory.Callback) definitionOption {
+	return func(f *factory.Factory) error {
+		if !f.CanHaveCallbacks {
+			return fmt.Errorf(callbackInAssociationErr, "BeforeDelete")
+		}
+
+		f.BeforeDeleteCallbacks = append(f.BeforeDeleteCallbacks, func(model interface{}, e *factory.Evaluator) error {
+			return callback(model)
+		})
+		return nil
+	}
+}
+
+// BeforeDeleteWithEvaluator sets callback called before the model struct been deleted by factory.Delete.
+// The callback can access the database executor and the context by the Evaluator.
+func BeforeDeleteWithEvaluator(callback factory.EvaluatorCallback) definitionOption {
+	return func(f *factory.Factory) error {
+		if !f.CanHaveCallbacks {
+			return fmt.Errorf(callbackInAssociationErr, "BeforeDeleteWithEvaluator")
+		}
+
+		f.BeforeDeleteCallbacks = append(f.BeforeDeleteCallbacks, callback)
+		return nil
+	}
+}
+
+// AfterDelete sets callback called after the model struct been deleted by factory.Delete.
+func AfterDelete(callback factory.Callback) definitionOption {
+	return func(f *factory.Factory) error {
+		if !f.CanHaveCallbacks {
+			return fmt.Errorf(callbackInAssociationErr, "AfterDelete")
+		}
+
+		f.AfterDeleteCallbacks = append(f.AfterDeleteCallbacks, func(model interface{}, e *factory.Evaluator) error {
+			return callback(model)
+		})
+		return nil
+	}
+}
+
+// AfterDeleteWithEvaluator sets callback called after the model struct been deleted by factory.Delete.
+// The callback can access the database executor and the context by the Evaluator.
+func AfterDeleteWithEvaluator(callback factory.EvaluatorCallback) definitionOption {
+	return func(f *factory.Factory) error {
+		if !f.CanHaveCallbacks {
+			return fmt.Errorf(callbackInAssociationErr, "AfterDeleteWithEvaluator")
+		}
+
+		f.AfterDeleteCallbacks = append(f.AfterDeleteCallbacks, callback)
+		return nil
+	}
+}
+
 // NewFactory defines a factory of a model struct.
 // Parameter model is the model struct instance(or struct instance pointer).
 // Parameter table represents which database table this model will be saved.
@@ -255,14 +720,47 @@ func AfterCreate(callback factory.Callback) definitionOption {
 // 	}),
 // )
 //
+// NewFactory panics if there are any errors in the definition. Use NewFactoryE to get the errors instead.
 func NewFactory(model interface{}, table string, opts ...definitionOption) *factory.Factory {
+	f, err := NewFactoryE(model, table, opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	return f
+}
+
+// NewFactoryE defines a factory of a model struct like NewFactory, but returns the errors instead of panicking.
+// All the options are applied, and the errors of them are collected into Errors.
+// It returns nil factory if there are any errors.
+func NewFactoryE(model interface{}, table string, opts ...definitionOption) (*factory.Factory, error) {
 	f := newDefaultFactory(model, table)
 
-	for _, opt := range opts {
-		err := opt(f)
-		if err != nil {
-			panic(err)
-		}
+	if err := applyDefinitionOptions(f, opts); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Register registers factory f by name in factory.DefaultRegistry, and returns f.
+// It panics if the name has been registered. Usage example:
+//
+// var UserFactory = Register("user", NewFactory(User{}, "user_table",
+// 	Field("Name", "test name"),
+// ))
+//
+// err := factory.DefaultRegistry.Build("user").To(user)
+//
+func Register(name string, f *factory.Factory) *factory.Factory {
+	return RegisterIn(factory.DefaultRegistry, name, f)
+}
+
+// RegisterIn registers factory f by name in registry r, and returns f.
+// It panics if the name has been registered in r.
+func RegisterIn(r *factory.Registry, name string, f *factory.Factory) *factory.Factory {
+	if err := r.Register(name, f); err != nil {
+		panic(err)
 	}
 
 	return f
@@ -327,23 +825,19 @@ func structFieldByName(typ reflect.Type, name string) (*reflect.StructField, boo
 	return field, true
 }
 
-func definedField(f *factory.Factory, name string) bool {
-	// FiledValues
-	if _, ok := f.FiledValues[name]; ok {
-		return true
-	}
-	// SequenceFiledValues
-	if _, ok := f.SequenceFiledValues[name]; ok {
-		return true
+func isSliceOf(typ reflect.Type, elemType reflect.Type) bool {
+	if typ.Kind() != reflect.Slice {
+		return false
 	}
-	// DynamicFieldValues
-	if _, ok := f.DynamicFieldValues[name]; ok {
-		return true
-	}
-	// AssociationFieldValues
-	if _, ok := f.AssociationFieldValues[name]; ok {
-		return true
+
+	typ = typ.Elem()
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
 	}
+	return typ == elemType
+}
 
-	return false
+func definedField(f *factory.Factory, name string) bool {
+	_, ok := f.FieldValue(name)
+	return ok
 }
@@ -1,9 +1,11 @@
 package def_test
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 
+	"github.com/nauyey/factory"
 	"github.com/nauyey/factory/def"
 )
 
@@ -21,6 +23,12 @@ type testBlog struct {
 	Content  string
 	AuthorID int64
 	Author   *testUser
+	Tags     []*testTag
+}
+
+type testTag struct {
+	ID   int64
+	Name string
 }
 
 func TestDuplicateDefinition(t *testing.T) {
@@ -163,3 +171,299 @@ func TestDuplicateDefinition(t *testing.T) {
 		),
 	)
 }
+
+func TestManyToManyFieldType(t *testing.T) {
+	const invalidManyToManyFieldErr = "as many-to-many association of"
+
+	tagFactory := def.NewFactory(testTag{}, "")
+	userFactory := def.NewFactory(testUser{}, "")
+
+	// test field which is a slice of the association model
+	def.NewFactory(testBlog{}, "",
+		def.ManyToMany("Tags", tagFactory, "blog_tags", "blog_id", "tag_id", 2),
+	)
+
+	// test field which isn't a slice of the association model
+	(func() {
+		defer func() {
+			err := recover()
+			if err == nil {
+				t.Fatalf("def.NewFactory should panic by invalid many-to-many field")
+			}
+			if ok := strings.Contains(err.(error).Error(), invalidManyToManyFieldErr); !ok {
+				t.Fatalf("expects err: \"%s\" contains \"%s\"", err.(error).Error(), invalidManyToManyFieldErr)
+			}
+		}()
+
+		def.NewFactory(testBlog{}, "",
+			def.ManyToMany("Tags", userFactory, "blog_tags", "blog_id", "tag_id", 2),
+		)
+	})()
+
+	// test negative count
+	(func() {
+		const invalidManyToManyCountErr = "invalid count -1"
+
+		defer func() {
+			err := recover()
+			if err == nil {
+				t.Fatalf("def.NewFactory should panic by negative many-to-many count")
+			}
+			if ok := strings.Contains(err.(error).Error(), invalidManyToManyCountErr); !ok {
+				t.Fatalf("expects err: \"%s\" contains \"%s\"", err.(error).Error(), invalidManyToManyCountErr)
+			}
+		}()
+
+		def.NewFactory(testBlog{}, "",
+			def.ManyToMany("Tags", tagFactory, "blog_tags", "blog_id", "tag_id", -1),
+		)
+	})()
+
+	// test nil factory
+	_, err := def.NewFactoryE(testBlog{}, "",
+		def.ManyToMany("Tags", nil, "blog_tags", "blog_id", "tag_id", 2),
+	)
+	if err == nil || !strings.Contains(err.Error(), "factory is nil") {
+		t.Errorf("expects err: \"%v\" contains \"factory is nil\"", err)
+	}
+}
+
+func TestPolymorphicType(t *testing.T) {
+	const undefinedPolymorphicErr = "undefined polymorphic association Author"
+
+	userFactory := def.NewFactory(testUser{}, "")
+
+	// test def.PolymorphicType without def.PolymorphicAssociation
+	_, err := def.NewFactoryE(testBlog{}, "",
+		def.Association("Author", "AuthorID", "ID", userFactory),
+		def.PolymorphicType("Author", userFactory, "users"),
+	)
+	if err == nil || !strings.Contains(err.Error(), undefinedPolymorphicErr) {
+		t.Errorf("expects err: \"%v\" contains \"%s\"", err, undefinedPolymorphicErr)
+	}
+
+	// test def.PolymorphicType with nil factory
+	type testNote struct {
+		CommentableType string
+		CommentableID   int64
+		Commentable     interface{}
+	}
+	_, err = def.NewFactoryE(testNote{}, "",
+		def.PolymorphicAssociation("Commentable", "CommentableType", "CommentableID", "ID", nil),
+		def.PolymorphicType("Commentable", nil, "users"),
+	)
+	if err == nil || !strings.Contains(err.Error(), "factory is nil") {
+		t.Errorf("expects err: \"%v\" contains \"factory is nil\"", err)
+	}
+}
+
+func TestStrategy(t *testing.T) {
+	const strategyOutOfAssociationErr = "is only allowed in Associations"
+
+	userFactory := def.NewFactory(testUser{}, "")
+
+	// test def.Strategy in def.Association
+	def.NewFactory(testBlog{}, "",
+		def.Association("Author", "AuthorID", "ID", userFactory,
+			def.Strategy(factory.StrategyBuild),
+		),
+	)
+
+	// test def.Strategy out of def.Association
+	(func() {
+		defer func() {
+			err := recover()
+			if err == nil {
+				t.Fatalf("def.NewFactory should panic by def.Strategy out of association")
+			}
+			if ok := strings.Contains(err.(error).Error(), strategyOutOfAssociationErr); !ok {
+				t.Fatalf("expects err: \"%s\" contains \"%s\"", err.(error).Error(), strategyOutOfAssociationErr)
+			}
+		}()
+
+		def.NewFactory(testUser{}, "",
+			def.Strategy(factory.StrategyBuild),
+		)
+	})()
+}
+
+func TestTransient(t *testing.T) {
+	const transientFieldConflictErr = "conflicts with the field of"
+
+	(func() {
+		defer func() {
+			err := recover()
+			if err == nil {
+				t.Fatalf("def.NewFactory should panic by transient field conflicting with struct field")
+			}
+			if ok := strings.Contains(err.(error).Error(), transientFieldConflictErr); !ok {
+				t.Fatalf("expects err: \"%s\" contains \"%s\"", err.(error).Error(), transientFieldConflictErr)
+			}
+		}()
+
+		def.NewFactory(testUser{}, "",
+			def.Transient("Name", "test name"),
+		)
+	})()
+}
+
+func TestSequenceRef(t *testing.T) {
+	const (
+		duplicateSequenceErr = "duplicate definition of sequence"
+		undefinedSequenceErr = "undefined sequence"
+	)
+
+	r := factory.NewRegistry()
+	emailSequence := def.NewSequenceIn(r, "def test email", 1, func(n int64) (interface{}, error) {
+		return n, nil
+	})
+
+	// test def.SequenceRefIn with defined sequence
+	def.NewFactory(testUser{}, "",
+		def.SequenceRefIn(r, "ID", emailSequence),
+	)
+
+	// test duplicate def.NewSequence
+	(func() {
+		defer func() {
+			err := recover()
+			if err == nil {
+				t.Fatalf("def.NewSequence should panic by duplicate sequence definition")
+			}
+			if ok := strings.Contains(err.(error).Error(), duplicateSequenceErr); !ok {
+				t.Fatalf("expects err: \"%s\" contains \"%s\"", err.(error).Error(), duplicateSequenceErr)
+			}
+		}()
+
+		def.NewSequenceIn(r, emailSequence, 1, func(n int64) (interface{}, error) {
+			return n, nil
+		})
+	})()
+
+	// test def.SequenceRef with undefined sequence
+	(func() {
+		defer func() {
+			err := recover()
+			if err == nil {
+				t.Fatalf("def.NewFactory should panic by undefined sequence")
+			}
+			if ok := strings.Contains(err.(error).Error(), undefinedSequenceErr); !ok {
+				t.Fatalf("expects err: \"%s\" contains \"%s\"", err.(error).Error(), undefinedSequenceErr)
+			}
+		}()
+
+		def.NewFactory(testUser{}, "",
+			def.SequenceRef("ID", emailSequence),
+		)
+	})()
+}
+
+func TestSequenceFromDB(t *testing.T) {
+	const invalidDBSequenceFieldErr = "as sequence field seeded from database"
+
+	// test def.SequenceFromDB with integer field
+	def.NewFactory(testUser{}, "",
+		def.SequenceFromDB("ID", "id"),
+	)
+
+	// test def.SequenceFromDB with non-integer field
+	(func() {
+		defer func() {
+			err := recover()
+			if err == nil {
+				t.Fatalf("def.NewFactory should panic by non-integer sequence field seeded from database")
+			}
+			if ok := strings.Contains(err.(error).Error(), invalidDBSequenceFieldErr); !ok {
+				t.Fatalf("expects err: \"%s\" contains \"%s\"", err.(error).Error(), invalidDBSequenceFieldErr)
+			}
+		}()
+
+		def.NewFactory(testUser{}, "",
+			def.SequenceFromDB("Name", "name"),
+		)
+	})()
+}
+
+func TestNewFactoryE(t *testing.T) {
+	userFactory := def.NewFactory(testUser{}, "")
+
+	// test def.NewFactoryE without errors
+	f, err := def.NewFactoryE(testUser{}, "",
+		def.Field("Name", "test name"),
+	)
+	if err != nil {
+		t.Fatalf("def.NewFactoryE failed with error: %v", err)
+	}
+	if f == nil {
+		t.Fatalf("def.NewFactoryE failed with nil factory")
+	}
+
+	// test def.NewFactoryE collects all errors
+	f, err = def.NewFactoryE(testBlog{}, "",
+		def.Field("NotExist", "value"),
+		def.Field("Title", 1),
+		def.Field("Content", "content"),
+		def.Field("Content", "content"),
+		def.Trait("trait",
+			def.Field("Title", 2),
+			def.Trait("nested trait"),
+		),
+		def.Trait("teenager", def.Field("Content", "teenager content")),
+		def.Trait("teenager"),
+		def.Association("Author", "AuthorID", "ID", userFactory,
+			def.Field("Name", 3),
+		),
+	)
+	if f != nil {
+		t.Errorf("def.NewFactoryE should return nil factory with errors")
+	}
+	errs, ok := err.(def.Errors)
+	if !ok {
+		t.Fatalf("def.NewFactoryE failed with error type %T, want def.Errors", err)
+	}
+	if len(errs) != 7 {
+		t.Fatalf("def.NewFactoryE failed with %d errors, want 7 errors: %v", len(errs), err)
+	}
+	for i, want := range []string{
+		"invalid field name NotExist",
+		"cannot use value (type int) as type string of field Title",
+		"duplicate definition of field Content",
+		"Trait trait error: cannot use value (type int) as type string of field Title",
+		"Trait trait error: Trait nested trait error: nested traits is not allowed",
+		"duplicate definition of Trait teenager",
+		"association Author error: cannot use value (type int) as type string of field Name",
+	} {
+		if ok := strings.Contains(errs[i].Error(), want); !ok {
+			t.Errorf("expects err: \"%s\" contains \"%s\"", errs[i].Error(), want)
+		}
+	}
+}
+
+// registerRuns makes the names registered in factory.DefaultRegistry unique when the tests run repeatedly.
+var registerRuns int
+
+func TestRegister(t *testing.T) {
+	const duplicateFactoryNameErr = "duplicate registration of factory"
+
+	registerRuns++
+	name := fmt.Sprintf("def test user %d", registerRuns)
+	userFactory := def.Register(name, def.NewFactory(testUser{}, ""))
+	if f, ok := factory.Lookup(name); !ok || f != userFactory {
+		t.Fatalf("def.Register failed with factory=%v, ok=%v", f, ok)
+	}
+
+	// test duplicate def.Register
+	(func() {
+		defer func() {
+			err := recover()
+			if err == nil {
+				t.Fatalf("def.Register should panic by duplicate name")
+			}
+			if ok := strings.Contains(err.(error).Error(), duplicateFactoryNameErr); !ok {
+				t.Fatalf("expects err: \"%s\" contains \"%s\"", err.(error).Error(), duplicateFactoryNameErr)
+			}
+		}()
+
+		def.Register(name, userFactory)
+	})()
+}
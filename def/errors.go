@@ -0,0 +1,62 @@
+package def
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nauyey/factory"
+)
+
+// Errors collects all the errors encountered when a factory is defined.
+type Errors []error
+
+// Error returns the messages of all the errors.
+func (errs Errors) Error() string {
+	if len(errs) == 1 {
+		return errs[0].Error()
+	}
+
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = "* " + err.Error()
+	}
+	return fmt.Sprintf("%d errors occurred:\n%s", len(errs), strings.Join(messages, "\n"))
+}
+
+// Unwrap returns the errors, so they can be inspected by errors.Is and errors.As.
+func (errs Errors) Unwrap() []error {
+	return errs
+}
+
+// append appends err to errs. The errors collected in err are appended one by one.
+func (errs Errors) append(err error) Errors {
+	if nested, ok := err.(Errors); ok {
+		return append(errs, nested...)
+	}
+	return append(errs, err)
+}
+
+// wrap wraps each of the errors collected in err by format, which takes name and the error with verb %w.
+func wrap(err error, format string, name string) Errors {
+	var errs Errors
+	for _, e := range Errors(nil).append(err) {
+		errs = append(errs, fmt.Errorf(format, name, e))
+	}
+	return errs
+}
+
+// applyDefinitionOptions applies all the options to factory f.
+// It returns Errors collecting the errors of all the options, or nil if there isn't any error.
+func applyDefinitionOptions(f *factory.Factory, opts []definitionOption) error {
+	var errs Errors
+	for _, opt := range opts {
+		if err := opt(f); err != nil {
+			errs = errs.append(err)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
@@ -0,0 +1,233 @@
+package factory_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// testDriver is a fake database/sql driver keeping rows in memory.
+// It only understands the SQL statements generated by factory:
+// INSERT INTO ... VALUES ..., SELECT ... FROM ... WHERE ... and DELETE FROM ... WHERE ...
+// Transactions restore the rows when they are rolled back, but aren't isolated from the other connections.
+type testDriver struct {
+	mux       sync.Mutex
+	databases map[string]*testDatabase
+}
+
+// testDatabase stores the rows of each table, and each row maps column names to values.
+type testDatabase struct {
+	tables map[string][]map[string]driver.Value
+}
+
+var (
+	testDriverOnce     sync.Once
+	testDriverInstance = &testDriver{databases: map[string]*testDatabase{}}
+	testDatabaseRuns   int
+
+	insertPattern = regexp.MustCompile(`^INSERT INTO (\w+) \(([\w,]+)\) VALUES`)
+	selectPattern = regexp.MustCompile(`^SELECT ([\w,]+) FROM (\w+) `)
+	deletePattern = regexp.MustCompile(`^DELETE FROM (\w+) `)
+	wherePattern  = regexp.MustCompile(`(\w+)=\?`)
+)
+
+// openTestDB opens a new empty database of the fake driver.
+// It returns the connection and the database to inspect the rows.
+func openTestDB() (*sql.DB, *testDatabase) {
+	testDriverOnce.Do(func() {
+		sql.Register("factory_test", testDriverInstance)
+	})
+
+	testDriverInstance.mux.Lock()
+	testDatabaseRuns++
+	name := fmt.Sprintf("test database %d", testDatabaseRuns)
+	database := &testDatabase{tables: map[string][]map[string]driver.Value{}}
+	testDriverInstance.databases[name] = database
+	testDriverInstance.mux.Unlock()
+
+	db, _ := sql.Open("factory_test", name)
+	return db, database
+}
+
+// rows returns the rows of table.
+func (database *testDatabase) rows(table string) []map[string]driver.Value {
+	return database.tables[table]
+}
+
+func (d *testDriver) Open(name string) (driver.Conn, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	database, ok := d.databases[name]
+	if !ok {
+		return nil, fmt.Errorf("undefined test database %s", name)
+	}
+	return &testConn{driver: d, database: database}, nil
+}
+
+type testConn struct {
+	driver   *testDriver
+	database *testDatabase
+}
+
+func (c *testConn) Prepare(query string) (driver.Stmt, error) {
+	return &testStmt{conn: c, query: query}, nil
+}
+
+func (c *testConn) Close() error {
+	return nil
+}
+
+func (c *testConn) Begin() (driver.Tx, error) {
+	c.driver.mux.Lock()
+	defer c.driver.mux.Unlock()
+
+	// the rows are never modified in place, so copying the slices of rows is enough
+	tables := map[string][]map[string]driver.Value{}
+	for table, rows := range c.database.tables {
+		tables[table] = append([]map[string]driver.Value{}, rows...)
+	}
+	return &testTx{conn: c, tables: tables}, nil
+}
+
+// testTx keeps the rows of the database when the transaction begins.
+type testTx struct {
+	conn   *testConn
+	tables map[string][]map[string]driver.Value
+}
+
+func (tx *testTx) Commit() error {
+	return nil
+}
+
+func (tx *testTx) Rollback() error {
+	tx.conn.driver.mux.Lock()
+	defer tx.conn.driver.mux.Unlock()
+
+	tx.conn.database.tables = tx.tables
+	return nil
+}
+
+type testStmt struct {
+	conn  *testConn
+	query string
+}
+
+func (s *testStmt) Close() error {
+	return nil
+}
+
+func (s *testStmt) NumInput() int {
+	return -1
+}
+
+func (s *testStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.conn.driver.mux.Lock()
+	defer s.conn.driver.mux.Unlock()
+
+	tables := s.conn.database.tables
+
+	if match := insertPattern.FindStringSubmatch(s.query); match != nil {
+		table, columns := match[1], strings.Split(match[2], ",")
+		if len(columns) != len(args) {
+			return nil, fmt.Errorf("got %d arguments, want %d in %s", len(args), len(columns), s.query)
+		}
+		row := map[string]driver.Value{}
+		for i, column := range columns {
+			row[column] = args[i]
+		}
+		tables[table] = append(tables[table], row)
+		return testResult{lastInsertID: int64(len(tables[table])), rowsAffected: 1}, nil
+	}
+
+	if match := deletePattern.FindStringSubmatch(s.query); match != nil {
+		table := match[1]
+		kept := []map[string]driver.Value{}
+		deleted := 0
+		for _, row := range tables[table] {
+			if matchRow(row, s.query, args) {
+				deleted++
+				continue
+			}
+			kept = append(kept, row)
+		}
+		tables[table] = kept
+		return testResult{rowsAffected: int64(deleted)}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported statement %s", s.query)
+}
+
+func (s *testStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.conn.driver.mux.Lock()
+	defer s.conn.driver.mux.Unlock()
+
+	match := selectPattern.FindStringSubmatch(s.query)
+	if match == nil {
+		return nil, fmt.Errorf("unsupported query %s", s.query)
+	}
+
+	columns, table := strings.Split(match[1], ","), match[2]
+	rows := &testRows{columns: columns}
+	for _, row := range s.conn.database.tables[table] {
+		if !matchRow(row, s.query, args) {
+			continue
+		}
+		values := make([]driver.Value, len(columns))
+		for i, column := range columns {
+			values[i] = row[column]
+		}
+		rows.values = append(rows.values, values)
+	}
+	return rows, nil
+}
+
+// matchRow reports whether row matches the conditions of the WHERE clause of query.
+func matchRow(row map[string]driver.Value, query string, args []driver.Value) bool {
+	where := query[strings.Index(query, "WHERE"):]
+	for i, match := range wherePattern.FindAllStringSubmatch(where, -1) {
+		if fmt.Sprint(row[match[1]]) != fmt.Sprint(args[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+type testResult struct {
+	lastInsertID int64
+	rowsAffected int64
+}
+
+func (r testResult) LastInsertId() (int64, error) {
+	return r.lastInsertID, nil
+}
+
+func (r testResult) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
+}
+
+type testRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *testRows) Columns() []string {
+	return r.columns
+}
+
+func (r *testRows) Close() error {
+	return nil
+}
+
+func (r *testRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
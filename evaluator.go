@@ -0,0 +1,315 @@
+package factory
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+)
+
+const (
+	cyclicFieldDependencyErr = "cyclic dependency of fields: %s"
+	unevaluatedFieldReadErr  = "dynamic field %s reads fields which haven't been evaluated: %s, define it by def.DependentField and read them by Evaluator.Field"
+)
+
+// Evaluator gives value generators and callbacks access to the transient fields
+// and the other fields of the model instance being generated.
+// Transient fields aren't model struct fields. They are defined by def.Transient,
+// and can be overridden by WithTransient.
+// It also tells the strategy, the traits, the database executor and the context in use.
+type Evaluator struct {
+	transients map[string]interface{}
+	instance   reflect.Value
+	strategy   string
+	traits     []string
+	executor   Executor
+	ctx        context.Context
+	// generators holds the DynamicFieldValue and DependentFieldValue generators
+	// of fields which haven't been evaluated
+	generators map[string]interface{}
+	// generatorNames holds the names of generated fields in the order they are defined
+	generatorNames []string
+	// evaluating is the stack of fields being evaluated
+	evaluating []string
+	// checkReads makes evaluate check the DynamicFieldValue generators don't read the fields which haven't been evaluated
+	checkReads bool
+}
+
+// newEvaluator creates an Evaluator instance for a blueprint generating the model instance
+// by strategy with database executor db.
+func newEvaluator(bp *blueprint, instance reflect.Value, strategy string, db Executor) *Evaluator {
+	if bp.strategy != "" {
+		strategy = bp.strategy
+	}
+
+	return &Evaluator{
+		transients: makeBlueprintTransients(bp),
+		instance:   instance,
+		strategy:   strategy,
+		traits:     bp.traits,
+		executor:   db,
+		ctx:        bp.context(),
+		generators: map[string]interface{}{},
+		checkReads: bp.checkReads,
+	}
+}
+
+// Transient returns the value of a transient field.
+// It returns nil if the transient field isn't defined.
+func (e *Evaluator) Transient(name string) interface{} {
+	return e.transients[name]
+}
+
+// Strategy returns the name of the strategy generating the model instance,
+// like StrategyBuild, StrategyCreate, StrategyStub or the name of a user-defined strategy.
+// It returns StrategyDelete in the callbacks executed by Delete.
+func (e *Evaluator) Strategy() string {
+	return e.strategy
+}
+
+// Traits returns the traits in use, in the order they are applied.
+func (e *Evaluator) Traits() []string {
+	return append([]string{}, e.traits...)
+}
+
+// Executor returns the database executor in use, which is set by WithExecutor or SetDB.
+// So callbacks can save related records in the same transaction.
+// It returns nil when nothing is saved into database, like in BuildStubbed.
+func (e *Evaluator) Executor() Executor {
+	return e.executor
+}
+
+// Context returns the context in use, which is set by WithContext.
+// It returns context.Background() if no context is set.
+func (e *Evaluator) Context() context.Context {
+	return e.ctx
+}
+
+// Rand returns the random number generator seeded by Seed.
+// Generators using it generate the same data with the same seed.
+func (e *Evaluator) Rand() *rand.Rand {
+	return Rand()
+}
+
+// Field returns the value of a field of the model instance being generated.
+// If the field is a dynamic field or a dependent field which hasn't been evaluated,
+// it will be evaluated first. So a DependentFieldValue generator can depend on other generated fields
+// no matter which order they are defined in.
+// It returns error if the fields depend on each other cyclically.
+func (e *Evaluator) Field(name string) (interface{}, error) {
+	if err := e.evaluate(name); err != nil {
+		return nil, err
+	}
+
+	return instanceFieldValue(e.instance, name), nil
+}
+
+// addGenerator adds the value generator of a field to be evaluated.
+func (e *Evaluator) addGenerator(name string, generator interface{}) {
+	e.generators[name] = generator
+	e.generatorNames = append(e.generatorNames, name)
+}
+
+// evaluateAll evaluates all the fields which haven't been evaluated in the order they are added.
+func (e *Evaluator) evaluateAll() error {
+	for _, name := range e.generatorNames {
+		if err := e.evaluate(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// evaluate calls the generator of field name and sets the field value, if the field hasn't been evaluated.
+func (e *Evaluator) evaluate(name string) error {
+	generator, ok := e.generators[name]
+	if !ok {
+		return nil
+	}
+
+	for i, evaluatingName := range e.evaluating {
+		if evaluatingName == name {
+			cycle := append(append([]string{}, e.evaluating[i:]...), name)
+			return fmt.Errorf(cyclicFieldDependencyErr, strings.Join(cycle, " -> "))
+		}
+	}
+
+	e.evaluating = append(e.evaluating, name)
+	defer func() {
+		e.evaluating = e.evaluating[:len(e.evaluating)-1]
+	}()
+
+	var (
+		fieldValue interface{}
+		err        error
+	)
+	switch generator := generator.(type) {
+	case DynamicFieldValue:
+		fieldValue, err = e.evaluateDynamic(name, generator)
+	case DependentFieldValue:
+		fieldValue, err = generator(e.instance.Addr().Interface(), e)
+	}
+	if err != nil {
+		return err
+	}
+
+	delete(e.generators, name)
+	return setInstanceGeneratedFieldValue(e.instance, name, fieldValue)
+}
+
+// evaluateDynamic calls the DynamicFieldValue generator of field name with the model instance.
+//
+// A DynamicFieldValue generator reads the model instance directly, so it gets zero values
+// from the generated fields which haven't been evaluated. When checkReads is set, like by Lint,
+// the generator is called again for each of those fields, with the field changed to another value.
+// If the generated value changes with it, and the generator is deterministic, it returns error.
+// The random number generator is reseeded by the same seed for each call,
+// so the generators of random data are checked as well.
+// The generator is called more than once in this case, so the check isn't done by the strategies.
+func (e *Evaluator) evaluateDynamic(name string, generator DynamicFieldValue) (interface{}, error) {
+	var unevaluated []string
+	for _, generatorName := range e.generatorNames {
+		if _, ok := e.generators[generatorName]; ok && generatorName != name {
+			unevaluated = append(unevaluated, generatorName)
+		}
+	}
+	if !e.checkReads || len(unevaluated) == 0 {
+		return generator(e.instance.Addr().Interface())
+	}
+
+	seed := Rand().Int63()
+	call := func(instance reflect.Value) (value interface{}, err error) {
+		prevSeed, prevSrc := randomSource.swap(seed, rand.NewSource(seed).(rand.Source64))
+		defer func() {
+			randomSource.swap(prevSeed, prevSrc)
+			if r := recover(); r != nil {
+				err = fmt.Errorf(lintPanicErr, r)
+			}
+		}()
+
+		return generator(instance.Addr().Interface())
+	}
+
+	value, err := call(e.instance)
+	if err != nil {
+		return nil, err
+	}
+
+	var readFields []string
+	for _, fieldName := range unevaluated {
+		instance, ok := changedInstance(e.instance, fieldName)
+		if !ok {
+			continue
+		}
+		if changed, err := call(instance); err != nil || !reflect.DeepEqual(changed, value) {
+			readFields = append(readFields, fieldName)
+		}
+	}
+	if len(readFields) == 0 {
+		return value, nil
+	}
+
+	// the value may change for other reasons, like counters in the generator
+	if again, err := call(e.instance); err != nil || !reflect.DeepEqual(again, value) {
+		return value, nil
+	}
+	return nil, fmt.Errorf(unevaluatedFieldReadErr, name, strings.Join(readFields, ", "))
+}
+
+// changedInstance returns a copy of the model instance, whose field fieldName is changed to another value.
+// It returns false if the field can't be changed without changing the model instance,
+// like the fields of the structs referenced by pointers, or there is no other value for it.
+func changedInstance(instance reflect.Value, fieldName string) (reflect.Value, bool) {
+	changed := reflect.New(instance.Type()).Elem()
+	changed.Set(instance)
+
+	field := changed
+	for _, name := range chainedFieldNameToFieldNames(fieldName) {
+		if field.Kind() != reflect.Struct {
+			return reflect.Value{}, false
+		}
+		field = field.FieldByName(name)
+	}
+
+	value, ok := otherValue(field)
+	if !ok || !field.CanSet() {
+		return reflect.Value{}, false
+	}
+	field.Set(value)
+	return changed, true
+}
+
+// otherValue returns a value of the type of v, which is different from v.
+// It returns false if there is no such value easy to make.
+func otherValue(v reflect.Value) (reflect.Value, bool) {
+	other := reflect.New(v.Type()).Elem()
+
+	switch v.Kind() {
+	case reflect.Bool:
+		other.SetBool(!v.Bool())
+	case reflect.String:
+		other.SetString(v.String() + "?")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		other.SetInt(v.Int() + 1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		other.SetUint(v.Uint() + 1)
+	case reflect.Float32, reflect.Float64:
+		other.SetFloat(v.Float() + 1)
+		if other.Float() == v.Float() {
+			return reflect.Value{}, false
+		}
+	case reflect.Ptr:
+		if !v.IsNil() {
+			return reflect.Value{}, false
+		}
+		other = reflect.New(v.Type().Elem())
+	case reflect.Slice:
+		other = reflect.MakeSlice(v.Type(), v.Len()+1, v.Len()+1)
+		reflect.Copy(other, v)
+	case reflect.Map:
+		if !v.IsNil() {
+			return reflect.Value{}, false
+		}
+		other = reflect.MakeMap(v.Type())
+	case reflect.Struct:
+		other.Set(v)
+		for i := 0; i < other.NumField(); i++ {
+			if !other.Field(i).CanSet() {
+				continue
+			}
+			if value, ok := otherValue(other.Field(i)); ok {
+				other.Field(i).Set(value)
+				return other, true
+			}
+		}
+		return reflect.Value{}, false
+	default:
+		return reflect.Value{}, false
+	}
+
+	return other, true
+}
+
+// makeBlueprintTransients create a new transient field value map of the factory model instance.
+// It chooses value for a transient field as following:
+// 1. apply the Factory Transients
+// 2. apply the Factory Traits Transients
+// 3. apply the blueprint transients
+func makeBlueprintTransients(bp *blueprint) map[string]interface{} {
+	transients := map[string]interface{}{}
+
+	for name, value := range bp.factory.Transients {
+		transients[name] = value
+	}
+	for _, trait := range bp.traits {
+		for name, value := range bp.factory.Traits[trait].Transients {
+			transients[name] = value
+		}
+	}
+	for name, value := range bp.transients {
+		transients[name] = value
+	}
+
+	return transients
+}
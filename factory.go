@@ -1,53 +1,155 @@
 package factory
 
 import (
+	"context"
+	"fmt"
 	"reflect"
 )
 
+// The strategies to generate associations.
+const (
+	// StrategyBuild builds the association but won't store it into database.
+	StrategyBuild = "build"
+	// StrategyCreate creates the association and stores it into database.
+	StrategyCreate = "create"
+	// StrategyStub builds the association with fake primary keys, as if it has been stored into database.
+	// It's used for all the associations of instances generated by BuildStubbed.
+	StrategyStub = "stub"
+)
+
+// StrategyDelete is the strategy returned by Evaluator.Strategy in the callbacks executed by Delete.
+const StrategyDelete = "delete"
+
 // Factory represents a factory defined by some model struct
 type Factory struct {
-	ModelType              reflect.Type
-	Table                  string
-	FiledValues            map[string]interface{}
-	SequenceFiledValues    map[string]*sequenceValue
-	DynamicFieldValues     map[string]DynamicFieldValue
-	AssociationFieldValues map[string]*AssociationFieldValue
-	Traits                 map[string]*Factory
-	AfterBuildCallbacks    []Callback
-	BeforeCreateCallbacks  []Callback
-	AfterCreateCallbacks   []Callback
+	ModelType             reflect.Type
+	Table                 string
+	Fields                []*FieldDefinition
+	Transients            map[string]interface{}
+	Traits                map[string]*Factory
+	BeforeBuildCallbacks  []EvaluatorCallback
+	AfterBuildCallbacks   []EvaluatorCallback
+	BeforeCreateCallbacks []EvaluatorCallback
+	AfterCreateCallbacks  []EvaluatorCallback
+	AfterStubCallbacks    []EvaluatorCallback
+	BeforeDeleteCallbacks []EvaluatorCallback
+	AfterDeleteCallbacks  []EvaluatorCallback
+
+	// Strategy is the strategy to generate the factory as an association.
+	// The strategy of the parent model instance is used if it is empty.
+	Strategy string
 
 	CanHaveAssociations bool
 	CanHaveTraits       bool
 	CanHaveCallbacks    bool
 }
 
+// FieldDefinition represents the definition of a field in a factory.
+// Value is the static value of the field, or one of the value generators:
+// sequence field value, DynamicFieldValue, DependentFieldValue, *AssociationFieldValue and *ManyToManyFieldValue.
+type FieldDefinition struct {
+	Name  string
+	Value interface{}
+}
+
+// AddFieldValue adds field value to factory by field name.
+// Fields are generated in the order they are added.
+func (f *Factory) AddFieldValue(name string, value interface{}) {
+	f.Fields = append(f.Fields, &FieldDefinition{
+		Name:  name,
+		Value: value,
+	})
+}
+
 // AddSequenceFiledValue adds sequence field value to factory by field name
 func (f *Factory) AddSequenceFiledValue(name string, first int64, value SequenceFieldValue) {
-	if f.SequenceFiledValues == nil {
-		f.SequenceFiledValues = map[string]*sequenceValue{}
+	f.AddFieldValue(name, newSequenceValue(first, value))
+}
+
+// AddDBSequenceFiledValue adds sequence field value to factory by field name,
+// whose values are the numbers of the sequence converted to the type of the field.
+// When the factory is created for the first time, the sequence is seeded above the maximum value of column in the factory's table.
+func (f *Factory) AddDBSequenceFiledValue(name string, column string) {
+	field, _ := structFieldByName(f.ModelType, name)
+	fieldType := field.Type
+
+	seqValue := newSequenceValue(1, func(n int64) (interface{}, error) {
+		return reflect.ValueOf(n).Convert(fieldType).Interface(), nil
+	})
+	seqValue.column = column
+
+	f.AddFieldValue(name, seqValue)
+}
+
+// AddSequenceRefFieldValue adds sequence field value to factory by field name,
+// whose values are generated by the named sequence defined by DefineSequence.
+// It returns error if the named sequence isn't defined.
+func (f *Factory) AddSequenceRefFieldValue(name string, sequenceName string) error {
+	return f.AddSequenceRefFieldValueIn(DefaultRegistry, name, sequenceName)
+}
+
+// AddSequenceRefFieldValueIn adds sequence field value to factory by field name,
+// whose values are generated by the named sequence defined in registry r.
+// It returns error if the named sequence isn't defined in r.
+func (f *Factory) AddSequenceRefFieldValueIn(r *Registry, name string, sequenceName string) error {
+	seqValue, ok := r.lookupSequence(sequenceName)
+	if !ok {
+		return fmt.Errorf(undefinedSequenceErr, sequenceName)
+	}
+
+	f.AddFieldValue(name, seqValue)
+	return nil
+}
+
+// FieldValue returns the value of the field defined in factory by field name.
+func (f *Factory) FieldValue(name string) (interface{}, bool) {
+	for _, field := range f.Fields {
+		if field.Name == name {
+			return field.Value, true
+		}
 	}
-	f.SequenceFiledValues[name] = newSequenceValue(first, value)
+	return nil, false
 }
 
 // sequenceValue defines the value of a sequence field.
+// The sequence is seeded from the maximum value of column in database if column isn't empty.
 type sequenceValue struct {
 	valueGenerateFunc SequenceFieldValue
 	sequence          *sequence
+	column            string
 }
 
 // value calculates the value of current sequenceValue
 func (seqValue *sequenceValue) value() (interface{}, error) {
+	if err := getSequencePartitionErr(); err != nil {
+		return nil, err
+	}
 	return seqValue.valueGenerateFunc(seqValue.sequence.next())
 }
 
+// valueAt calculates the value of number n without moving the sequence.
+// n is used as is, without the offset of the sequence partition.
+func (seqValue *sequenceValue) valueAt(n int64) (interface{}, error) {
+	return seqValue.valueGenerateFunc(n)
+}
+
+// seedFromDB seeds the sequence above the maximum value of the column in table tableName.
+// It queries database only once until the sequence is rewound.
+func (seqValue *sequenceValue) seedFromDB(ctx context.Context, db Executor, tableName string) error {
+	if seqValue.column == "" {
+		return nil
+	}
+
+	return seqValue.sequence.seedAbove(tableName+"."+seqValue.column, func() (int64, error) {
+		return selectMax(ctx, db, tableName, seqValue.column)
+	})
+}
+
 // newSequenceValue create a new SequenceValue instance
 func newSequenceValue(first int64, value SequenceFieldValue) *sequenceValue {
 	return &sequenceValue{
 		valueGenerateFunc: value,
-		sequence: &sequence{
-			first: first,
-		},
+		sequence:          newSequence(first),
 	}
 }
 
@@ -57,15 +159,101 @@ type SequenceFieldValue func(n int64) (interface{}, error)
 
 // DynamicFieldValue defines the value generator type of a field.
 // It's return result will be set as the value of the field dynamicly.
+// The model instance passed to it only has the generated fields defined before the field.
 type DynamicFieldValue func(model interface{}) (interface{}, error)
 
+// DependentFieldValue defines the value generator type of a field which depends on transient fields or other fields.
+// They can be read by the Evaluator. Reading another dynamic field or dependent field by Evaluator.Field
+// makes that field evaluated first.
+// It's return result will be set as the value of the field dynamicly.
+type DependentFieldValue func(model interface{}, e *Evaluator) (interface{}, error)
+
 // AssociationFieldValue represents a struct which contains data to generate value of a association field.
+// For polymorphic associations, TypeField is the name of the field which stores the type of the association,
+// and OriginalFactory may be nil until a factory is chosen when the model instance is generated.
+// TypeValues maps the model types of associations to the values of TypeField,
+// which are the struct names of the model types by default.
 type AssociationFieldValue struct {
 	ReferenceField            string
 	AssociationReferenceField string
+	TypeField                 string
+	TypeValues                map[reflect.Type]string
 	OriginalFactory           *Factory
 	Factory                   *Factory
+	// options override the association when it's generated, set by WithAssociation
+	options []factoryOption
+}
+
+// withOriginalFactory returns a copy of the association field value which generates value from originalFactory.
+// The field values overridden in the association definition are dropped if originalFactory is of another model type.
+func (value *AssociationFieldValue) withOriginalFactory(originalFactory *Factory) *AssociationFieldValue {
+	associationFieldValue := *value
+	associationFieldValue.OriginalFactory = originalFactory
+	if value.OriginalFactory == nil || value.OriginalFactory.ModelType != originalFactory.ModelType {
+		associationFieldValue.Factory = &Factory{
+			ModelType: originalFactory.ModelType,
+			Strategy:  value.Factory.Strategy,
+		}
+	}
+
+	return &associationFieldValue
+}
+
+// withStrategy returns a copy of the association field value which is generated by strategy.
+func (value *AssociationFieldValue) withStrategy(strategy string) *AssociationFieldValue {
+	associationFactory := *value.Factory
+	associationFactory.Strategy = strategy
+
+	associationFieldValue := *value
+	associationFieldValue.Factory = &associationFactory
+
+	return &associationFieldValue
+}
+
+// withOptions returns a copy of the association field value which is generated with options.
+func (value *AssociationFieldValue) withOptions(options []factoryOption) *AssociationFieldValue {
+	associationFieldValue := *value
+	associationFieldValue.options = append(append([]factoryOption{}, value.options...), options...)
+
+	return &associationFieldValue
+}
+
+// strategy returns the strategy to generate the association
+// when the parent model instance is generated by parentStrategy.
+func (value *AssociationFieldValue) strategy(parentStrategy string) string {
+	if value.Factory.Strategy != "" {
+		return value.Factory.Strategy
+	}
+	return parentStrategy
+}
+
+// typeValue returns the value of the type field of polymorphic association for model type modelType,
+// when the associated object is of type associationType.
+// It's the value set in TypeValues, or the struct name of associationType by default.
+func (value *AssociationFieldValue) typeValue(modelType, associationType reflect.Type) interface{} {
+	field, _ := structFieldByName(modelType, value.TypeField)
+
+	typeValue, ok := value.TypeValues[associationType]
+	if !ok {
+		typeValue = associationType.Name()
+	}
+	return reflect.ValueOf(typeValue).Convert(field.Type).Interface()
+}
+
+// ManyToManyFieldValue represents a struct which contains data to generate value of a many-to-many association field.
+// The associated instances are linked to the model instance by rows of JoinTable,
+// where ForeignKey references the model instance and AssociationForeignKey references the associated instance.
+type ManyToManyFieldValue struct {
+	JoinTable             string
+	ForeignKey            string
+	AssociationForeignKey string
+	Count                 int
+	OriginalFactory       *Factory
+	Factory               *Factory
 }
 
 // Callback defines the callback function type
 type Callback func(model interface{}) error
+
+// EvaluatorCallback defines the callback function type which can access transient fields by the Evaluator.
+type EvaluatorCallback func(model interface{}, e *Evaluator) error
@@ -0,0 +1,346 @@
+// Package fake provides generators of fake data, like names, e-mail addresses and phone numbers.
+// The generators return factory.DynamicFieldValue or factory.SequenceFieldValue functions,
+// which can be used in def.DynamicField and def.SequenceField directly.
+//
+// userFactory := def.NewFactory(User{}, "user_table",
+// 	def.DynamicField("Name", fake.Name()),
+// 	def.SequenceField("Email", 1, fake.UniqueEmail()),
+// 	def.DynamicField("Phone", fake.In(fake.Chinese).PhoneNumber()),
+// )
+//
+// The data sets of English and Chinese are built in. The generated values are strings,
+// except numbers generated by Int.
+// The random data is generated by factory.Rand, so it can be replayed by factory.Seed.
+package fake
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/nauyey/factory"
+)
+
+const invalidIntRangeErr = "invalid range [%d, %d] of Int, want min <= max"
+
+// intn returns a random number in [0, n) by the random number generator of package factory,
+// so the fake data can be replayed by factory.Seed.
+func intn(n int) int {
+	return factory.Rand().Intn(n)
+}
+
+func pick(values []string) string {
+	return values[intn(len(values))]
+}
+
+// Faker generates fake data from the data set of a locale.
+type Faker struct {
+	locale *Locale
+}
+
+// In returns a Faker which generates fake data from the data set of locale.
+func In(locale *Locale) *Faker {
+	return &Faker{locale: locale}
+}
+
+var defaultFaker = In(English)
+
+// FirstName returns a generator of first names.
+func (faker *Faker) FirstName() factory.DynamicFieldValue {
+	return func(model interface{}) (interface{}, error) {
+		return pick(faker.locale.FirstNames), nil
+	}
+}
+
+// LastName returns a generator of last names.
+func (faker *Faker) LastName() factory.DynamicFieldValue {
+	return func(model interface{}) (interface{}, error) {
+		return pick(faker.locale.LastNames), nil
+	}
+}
+
+// Name returns a generator of full names.
+func (faker *Faker) Name() factory.DynamicFieldValue {
+	return func(model interface{}) (interface{}, error) {
+		return faker.locale.NameFormat(pick(faker.locale.FirstNames), pick(faker.locale.LastNames)), nil
+	}
+}
+
+// Email returns a generator of e-mail addresses.
+// The addresses may repeat, use UniqueEmail for unique addresses.
+func (faker *Faker) Email() factory.DynamicFieldValue {
+	return func(model interface{}) (interface{}, error) {
+		return fmt.Sprintf("%s@%s", faker.username(), pick(faker.locale.EmailDomains)), nil
+	}
+}
+
+// UniqueEmail returns a sequence generator of e-mail addresses,
+// which are unique by the numbers of the sequence.
+func (faker *Faker) UniqueEmail() factory.SequenceFieldValue {
+	return func(n int64) (interface{}, error) {
+		return fmt.Sprintf("%s%d@%s", faker.username(), n, pick(faker.locale.EmailDomains)), nil
+	}
+}
+
+func (faker *Faker) username() string {
+	if len(faker.locale.Usernames) > 0 {
+		return pick(faker.locale.Usernames)
+	}
+	return strings.ToLower(pick(faker.locale.FirstNames) + "." + pick(faker.locale.LastNames))
+}
+
+// PhoneNumber returns a generator of phone numbers.
+func (faker *Faker) PhoneNumber() factory.DynamicFieldValue {
+	return func(model interface{}) (interface{}, error) {
+		return digits(pick(faker.locale.PhoneFormats)), nil
+	}
+}
+
+// Country returns a generator of country names.
+func (faker *Faker) Country() factory.DynamicFieldValue {
+	return func(model interface{}) (interface{}, error) {
+		return pick(faker.locale.Countries), nil
+	}
+}
+
+// State returns a generator of state or province names.
+func (faker *Faker) State() factory.DynamicFieldValue {
+	return func(model interface{}) (interface{}, error) {
+		return pick(faker.locale.States), nil
+	}
+}
+
+// City returns a generator of city names.
+func (faker *Faker) City() factory.DynamicFieldValue {
+	return func(model interface{}) (interface{}, error) {
+		return pick(faker.locale.Cities), nil
+	}
+}
+
+// Street returns a generator of street names.
+func (faker *Faker) Street() factory.DynamicFieldValue {
+	return func(model interface{}) (interface{}, error) {
+		return pick(faker.locale.Streets), nil
+	}
+}
+
+// Address returns a generator of full addresses.
+func (faker *Faker) Address() factory.DynamicFieldValue {
+	return func(model interface{}) (interface{}, error) {
+		locale := faker.locale
+		return locale.AddressFormat(intn(999)+1, pick(locale.Streets), pick(locale.Cities), pick(locale.States)), nil
+	}
+}
+
+// Company returns a generator of company names.
+func (faker *Faker) Company() factory.DynamicFieldValue {
+	return func(model interface{}) (interface{}, error) {
+		return faker.locale.companyName(), nil
+	}
+}
+
+func (locale *Locale) companyName() string {
+	return pick(locale.CompanyPrefixes) + locale.WordSeparator + pick(locale.CompanySuffixes)
+}
+
+// Words returns a generator of n words separated by the word separator of the locale.
+func (faker *Faker) Words(n int) factory.DynamicFieldValue {
+	return func(model interface{}) (interface{}, error) {
+		return faker.words(n), nil
+	}
+}
+
+// Sentence returns a generator of sentences of n words.
+func (faker *Faker) Sentence(n int) factory.DynamicFieldValue {
+	return func(model interface{}) (interface{}, error) {
+		return faker.sentence(n), nil
+	}
+}
+
+// Paragraph returns a generator of paragraphs of n sentences.
+func (faker *Faker) Paragraph(n int) factory.DynamicFieldValue {
+	return func(model interface{}) (interface{}, error) {
+		sentences := make([]string, n)
+		for i := range sentences {
+			sentences[i] = faker.sentence(intn(8) + 4)
+		}
+		return strings.Join(sentences, faker.locale.WordSeparator), nil
+	}
+}
+
+func (faker *Faker) words(n int) string {
+	words := make([]string, n)
+	for i := range words {
+		words[i] = pick(faker.locale.Words)
+	}
+	return strings.Join(words, faker.locale.WordSeparator)
+}
+
+func (faker *Faker) sentence(n int) string {
+	words := faker.words(n)
+	if words == "" {
+		return words
+	}
+
+	// capitalize the first letter, which has no effect on languages without cases
+	r, size := utf8.DecodeRuneInString(words)
+	return string(unicode.ToUpper(r)) + words[size:] + faker.locale.SentenceEnd
+}
+
+// FirstName returns a generator of English first names.
+func FirstName() factory.DynamicFieldValue {
+	return defaultFaker.FirstName()
+}
+
+// LastName returns a generator of English last names.
+func LastName() factory.DynamicFieldValue {
+	return defaultFaker.LastName()
+}
+
+// Name returns a generator of English full names.
+func Name() factory.DynamicFieldValue {
+	return defaultFaker.Name()
+}
+
+// Email returns a generator of e-mail addresses.
+// The addresses may repeat, use UniqueEmail for unique addresses.
+func Email() factory.DynamicFieldValue {
+	return defaultFaker.Email()
+}
+
+// UniqueEmail returns a sequence generator of e-mail addresses,
+// which are unique by the numbers of the sequence.
+func UniqueEmail() factory.SequenceFieldValue {
+	return defaultFaker.UniqueEmail()
+}
+
+// PhoneNumber returns a generator of US phone numbers.
+func PhoneNumber() factory.DynamicFieldValue {
+	return defaultFaker.PhoneNumber()
+}
+
+// Country returns a generator of English country names.
+func Country() factory.DynamicFieldValue {
+	return defaultFaker.Country()
+}
+
+// State returns a generator of US state names.
+func State() factory.DynamicFieldValue {
+	return defaultFaker.State()
+}
+
+// City returns a generator of English city names.
+func City() factory.DynamicFieldValue {
+	return defaultFaker.City()
+}
+
+// Street returns a generator of English street names.
+func Street() factory.DynamicFieldValue {
+	return defaultFaker.Street()
+}
+
+// Address returns a generator of US addresses.
+func Address() factory.DynamicFieldValue {
+	return defaultFaker.Address()
+}
+
+// Company returns a generator of English company names.
+func Company() factory.DynamicFieldValue {
+	return defaultFaker.Company()
+}
+
+// Words returns a generator of n lorem ipsum words.
+func Words(n int) factory.DynamicFieldValue {
+	return defaultFaker.Words(n)
+}
+
+// Sentence returns a generator of lorem ipsum sentences of n words.
+func Sentence(n int) factory.DynamicFieldValue {
+	return defaultFaker.Sentence(n)
+}
+
+// Paragraph returns a generator of lorem ipsum paragraphs of n sentences.
+func Paragraph(n int) factory.DynamicFieldValue {
+	return defaultFaker.Paragraph(n)
+}
+
+// IPv4 returns a generator of IPv4 addresses.
+func IPv4() factory.DynamicFieldValue {
+	return func(model interface{}) (interface{}, error) {
+		ip := net.IPv4(byte(intn(223)+1), byte(intn(256)), byte(intn(256)), byte(intn(254)+1))
+		return ip.String(), nil
+	}
+}
+
+// IPv6 returns a generator of IPv6 addresses.
+func IPv6() factory.DynamicFieldValue {
+	return func(model interface{}) (interface{}, error) {
+		ip := make(net.IP, net.IPv6len)
+		for i := range ip {
+			ip[i] = byte(intn(256))
+		}
+		// use the global unicast prefix 2000::/3
+		ip[0] = 0x20 | ip[0]&0x1f
+		return ip.String(), nil
+	}
+}
+
+// CreditCardNumber returns a generator of 16 digit card numbers which pass the Luhn check.
+// The numbers start with 4, in the shape of Visa card numbers, but they aren't real card numbers.
+func CreditCardNumber() factory.DynamicFieldValue {
+	return func(model interface{}) (interface{}, error) {
+		number := digits("4##############")
+		return number + string('0'+luhnCheckDigit(number)), nil
+	}
+}
+
+// Int returns a generator of int numbers in [min, max].
+// The generator returns error if max is less than min.
+func Int(min, max int) factory.DynamicFieldValue {
+	return func(model interface{}) (interface{}, error) {
+		if max < min {
+			return nil, fmt.Errorf(invalidIntRangeErr, min, max)
+		}
+
+		// the span is calculated in uint64, so it doesn't overflow for ranges like [math.MinInt64, math.MaxInt64]
+		span := uint64(max) - uint64(min)
+		if span == math.MaxUint64 {
+			return int(factory.Rand().Uint64()), nil
+		}
+		if span >= math.MaxInt64 {
+			return min + int(factory.Rand().Uint64()%(span+1)), nil
+		}
+		return min + int(factory.Rand().Int63n(int64(span)+1)), nil
+	}
+}
+
+// digits replaces the '#' characters in format by random digits.
+func digits(format string) string {
+	var b strings.Builder
+	for _, r := range format {
+		if r == '#' {
+			r = rune('0' + intn(10))
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// luhnCheckDigit calculates the check digit of number by the Luhn algorithm.
+func luhnCheckDigit(number string) byte {
+	sum := 0
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if (len(number)-i)%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return byte((10 - sum%10) % 10)
+}
@@ -0,0 +1,143 @@
+package fake
+
+import (
+	"net"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/nauyey/factory"
+)
+
+func generate(t *testing.T, value factory.DynamicFieldValue) string {
+	v, err := value(nil)
+	if err != nil {
+		t.Fatalf("generate fake data failed with error: %v", err)
+	}
+	return v.(string)
+}
+
+func TestLocales(t *testing.T) {
+	for _, locale := range []*Locale{English, Chinese} {
+		faker := In(locale)
+		for _, value := range []factory.DynamicFieldValue{
+			faker.FirstName(),
+			faker.LastName(),
+			faker.Name(),
+			faker.Email(),
+			faker.PhoneNumber(),
+			faker.Country(),
+			faker.State(),
+			faker.City(),
+			faker.Street(),
+			faker.Address(),
+			faker.Company(),
+			faker.Words(3),
+			faker.Sentence(5),
+			faker.Paragraph(2),
+		} {
+			v := generate(t, value)
+			if v == "" || !utf8.ValidString(v) {
+				t.Errorf("fake data of locale %s failed with invalid value %q", locale.Name, v)
+			}
+		}
+	}
+}
+
+func TestEmail(t *testing.T) {
+	emailPattern := regexp.MustCompile(`^[a-z.0-9]+@[a-z.]+$`)
+
+	for _, locale := range []*Locale{English, Chinese} {
+		if email := generate(t, In(locale).Email()); !emailPattern.MatchString(email) {
+			t.Errorf("Email of locale %s failed with email=%s", locale.Name, email)
+		}
+
+		email, err := In(locale).UniqueEmail()(42)
+		if err != nil {
+			t.Fatalf("UniqueEmail failed with error: %v", err)
+		}
+		if !emailPattern.MatchString(email.(string)) || !strings.Contains(email.(string), "42@") {
+			t.Errorf("UniqueEmail of locale %s failed with email=%s", locale.Name, email)
+		}
+	}
+}
+
+func TestSentence(t *testing.T) {
+	sentence := generate(t, Sentence(4))
+	if len(strings.Split(sentence, " ")) != 4 || !strings.HasSuffix(sentence, ".") {
+		t.Errorf("Sentence failed with sentence=%s", sentence)
+	}
+	if first := sentence[:1]; first != strings.ToUpper(first) {
+		t.Errorf("Sentence failed with sentence=%s, want it capitalized", sentence)
+	}
+
+	sentence = generate(t, In(Chinese).Sentence(4))
+	if !strings.HasSuffix(sentence, "。") {
+		t.Errorf("Chinese Sentence failed with sentence=%s", sentence)
+	}
+}
+
+func TestIP(t *testing.T) {
+	if ip := net.ParseIP(generate(t, IPv4())); ip == nil || ip.To4() == nil {
+		t.Errorf("IPv4 failed with invalid ip")
+	}
+	if ip := net.ParseIP(generate(t, IPv6())); ip == nil || ip.To4() != nil {
+		t.Errorf("IPv6 failed with invalid ip")
+	}
+}
+
+func TestCreditCardNumber(t *testing.T) {
+	number := generate(t, CreditCardNumber())
+	if len(number) != 16 || number[0] != '4' {
+		t.Fatalf("CreditCardNumber failed with number=%s", number)
+	}
+	if checkDigit := luhnCheckDigit(number[:15]); number[15] != '0'+checkDigit {
+		t.Errorf("CreditCardNumber failed with number=%s, want check digit %d", number, checkDigit)
+	}
+
+	// 7992739871 is the example of the Luhn algorithm with check digit 3
+	if checkDigit := luhnCheckDigit("7992739871"); checkDigit != 3 {
+		t.Errorf("luhnCheckDigit failed with checkDigit=%d, want 3", checkDigit)
+	}
+}
+
+func TestInt(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		v, _ := Int(3, 5)(nil)
+		if n := v.(int); n < 3 || n > 5 {
+			t.Fatalf("Int failed with n=%d, want n in [3, 5]", n)
+		}
+	}
+
+	// Test Int with a single number
+	if v, err := Int(7, 7)(nil); err != nil || v.(int) != 7 {
+		t.Errorf("Int(7, 7) failed with v=%v, err=%v, want v=7", v, err)
+	}
+
+	// Test Int with the full range of int
+	const maxInt = int(^uint(0) >> 1)
+	if _, err := Int(-maxInt-1, maxInt)(nil); err != nil {
+		t.Errorf("Int with the full range failed with error: %v", err)
+	}
+
+	// Test Int with max less than min
+	if _, err := Int(5, 3)(nil); err == nil || !strings.Contains(err.Error(), "invalid range [5, 3]") {
+		t.Errorf("Int(5, 3) failed with err=%v", err)
+	}
+}
+
+func TestSeed(t *testing.T) {
+	defer factory.Seed(factory.CurrentSeed())
+
+	factory.Seed(42)
+	name, email := generate(t, Name()), generate(t, Email())
+
+	factory.Seed(42)
+	if replayed := generate(t, Name()); replayed != name {
+		t.Errorf("Name with the same seed failed with name=%s, want name=%s", replayed, name)
+	}
+	if replayed := generate(t, Email()); replayed != email {
+		t.Errorf("Email with the same seed failed with email=%s, want email=%s", replayed, email)
+	}
+}
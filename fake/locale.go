@@ -0,0 +1,33 @@
+package fake
+
+// Locale represents the data set used to generate fake data in a language.
+// The '#' characters in PhoneFormats are replaced by random digits.
+type Locale struct {
+	Name string
+
+	FirstNames []string
+	LastNames  []string
+	// NameFormat combines the first name and the last name into a full name
+	NameFormat func(firstName, lastName string) string
+	// Usernames are ASCII names used in the local part of e-mail addresses
+	Usernames    []string
+	EmailDomains []string
+
+	PhoneFormats []string
+
+	Countries []string
+	States    []string
+	Cities    []string
+	Streets   []string
+	// AddressFormat combines the street number, street, city and state into an address
+	AddressFormat func(number int, street, city, state string) string
+
+	CompanyPrefixes []string
+	CompanySuffixes []string
+
+	Words []string
+	// WordSeparator joins words into sentences
+	WordSeparator string
+	// SentenceEnd ends sentences
+	SentenceEnd string
+}
@@ -0,0 +1,68 @@
+package fake
+
+import "fmt"
+
+// English is the data set of English.
+var English = &Locale{
+	Name: "en",
+
+	FirstNames: []string{
+		"James", "Mary", "John", "Patricia", "Robert", "Jennifer", "Michael", "Linda",
+		"William", "Elizabeth", "David", "Barbara", "Richard", "Susan", "Joseph", "Jessica",
+		"Thomas", "Sarah", "Charles", "Karen", "Daniel", "Nancy", "Matthew", "Lisa",
+		"Anthony", "Betty", "Mark", "Margaret", "Steven", "Sandra", "Paul", "Ashley",
+	},
+	LastNames: []string{
+		"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis",
+		"Rodriguez", "Martinez", "Hernandez", "Lopez", "Wilson", "Anderson", "Thomas", "Taylor",
+		"Moore", "Jackson", "Martin", "Lee", "Thompson", "White", "Harris", "Clark",
+		"Lewis", "Robinson", "Walker", "Young", "Allen", "King", "Wright", "Scott",
+	},
+	NameFormat: func(firstName, lastName string) string {
+		return firstName + " " + lastName
+	},
+	EmailDomains: []string{
+		"example.com", "example.net", "example.org",
+	},
+
+	PhoneFormats: []string{
+		"(###) ###-####", "###-###-####", "###.###.####", "+1 ### ### ####",
+	},
+
+	Countries: []string{
+		"United States", "United Kingdom", "Canada", "Australia", "New Zealand", "Ireland",
+		"Germany", "France", "Japan", "China", "India", "Brazil", "Mexico", "Spain",
+	},
+	States: []string{
+		"Alabama", "Alaska", "Arizona", "California", "Colorado", "Florida", "Georgia", "Illinois",
+		"Massachusetts", "Michigan", "New York", "Ohio", "Oregon", "Texas", "Virginia", "Washington",
+	},
+	Cities: []string{
+		"Springfield", "Riverside", "Franklin", "Greenville", "Bristol", "Clinton", "Fairview", "Salem",
+		"Madison", "Georgetown", "Arlington", "Ashland", "Burlington", "Manchester", "Oxford", "Jackson",
+	},
+	Streets: []string{
+		"Main Street", "Oak Street", "Pine Street", "Maple Avenue", "Cedar Lane", "Elm Street",
+		"Washington Avenue", "Lake Drive", "Hill Road", "Park Avenue", "Sunset Boulevard", "River Road",
+	},
+	AddressFormat: func(number int, street, city, state string) string {
+		return fmt.Sprintf("%d %s, %s, %s", number, street, city, state)
+	},
+
+	CompanyPrefixes: []string{
+		"Acme", "Globex", "Initech", "Umbrella", "Stark", "Wayne", "Cyberdyne", "Soylent",
+		"Hooli", "Vandelay", "Wonka", "Tyrell", "Aperture", "Gringotts", "Oscorp", "Monarch",
+	},
+	CompanySuffixes: []string{
+		"Inc", "LLC", "Group", "and Sons", "Corporation", "Industries", "Holdings", "Labs",
+	},
+
+	Words: []string{
+		"lorem", "ipsum", "dolor", "sit", "amet", "consectetur", "adipiscing", "elit",
+		"sed", "do", "eiusmod", "tempor", "incididunt", "ut", "labore", "et",
+		"dolore", "magna", "aliqua", "enim", "ad", "minim", "veniam", "quis",
+		"nostrud", "exercitation", "ullamco", "laboris", "nisi", "aliquip", "ex", "ea",
+	},
+	WordSeparator: " ",
+	SentenceEnd:   ".",
+}
@@ -0,0 +1,69 @@
+package fake
+
+import "fmt"
+
+// Chinese is the data set of simplified Chinese.
+var Chinese = &Locale{
+	Name: "zh",
+
+	FirstNames: []string{
+		"伟", "芳", "娜", "敏", "静", "丽", "强", "磊",
+		"军", "洋", "勇", "艳", "杰", "娟", "涛", "明",
+		"超", "秀英", "霞", "平", "刚", "桂英", "小明", "建国",
+	},
+	LastNames: []string{
+		"王", "李", "张", "刘", "陈", "杨", "黄", "赵",
+		"吴", "周", "徐", "孙", "马", "朱", "胡", "郭",
+		"何", "高", "林", "罗", "郑", "梁", "谢", "宋",
+	},
+	NameFormat: func(firstName, lastName string) string {
+		return lastName + firstName
+	},
+	Usernames: []string{
+		"wangwei", "lifang", "zhangna", "liumin", "chenjing", "yangli", "huangqiang", "zhaolei",
+		"wujun", "zhouyang", "xuyong", "sunyan", "majie", "zhujuan", "hutao", "guoming",
+	},
+	EmailDomains: []string{
+		"example.cn", "example.com.cn", "example.com",
+	},
+
+	PhoneFormats: []string{
+		"13#########", "15#########", "18#########", "+86 13# #### ####", "010-########",
+	},
+
+	Countries: []string{
+		"中国", "日本", "韩国", "美国", "英国", "法国", "德国", "俄罗斯",
+		"加拿大", "澳大利亚", "新加坡", "马来西亚", "泰国", "越南",
+	},
+	States: []string{
+		"北京市", "上海市", "天津市", "重庆市", "广东省", "浙江省", "江苏省", "四川省",
+		"湖北省", "湖南省", "山东省", "河南省", "福建省", "陕西省", "辽宁省", "云南省",
+	},
+	Cities: []string{
+		"广州", "深圳", "杭州", "南京", "成都", "武汉", "长沙", "济南",
+		"郑州", "福州", "西安", "沈阳", "昆明", "苏州", "宁波", "青岛",
+	},
+	Streets: []string{
+		"人民路", "解放路", "中山路", "建设路", "和平路", "新华路",
+		"长江路", "黄河路", "胜利路", "光明街", "文化路", "朝阳街",
+	},
+	AddressFormat: func(number int, street, city, state string) string {
+		return fmt.Sprintf("%s%s市%s%d号", state, city, street, number)
+	},
+
+	CompanyPrefixes: []string{
+		"华泰", "宏达", "鑫源", "天宇", "恒通", "中科", "盛世", "东方",
+		"新兴", "金桥", "远航", "星辰", "长城", "海纳", "博雅", "瑞丰",
+	},
+	CompanySuffixes: []string{
+		"科技有限公司", "网络科技有限公司", "贸易有限公司", "实业有限公司", "信息技术有限公司", "集团有限公司",
+	},
+
+	Words: []string{
+		"我们", "今天", "工作", "学习", "生活", "时间", "问题", "发展",
+		"社会", "经济", "技术", "数据", "系统", "用户", "服务", "产品",
+		"市场", "管理", "设计", "研究", "测试", "方法", "结果", "需要",
+	},
+	WordSeparator: "",
+	SentenceEnd:   "。",
+}
@@ -0,0 +1,119 @@
+package factory
+
+import (
+	"fmt"
+)
+
+const (
+	invalidElementOptionErr = "invalid options of element %d: %v"
+)
+
+// indexedField represents a field whose value is generated by the index of the model instance in a slice.
+type indexedField struct {
+	name  string
+	value func(i int) interface{}
+}
+
+// WithIndexedField sets the value of a specific field by the index of the model instance
+// generated by BuildSlice, BuildStubbedSlice, CreateSlice or GenerateSlice.
+// The value is checked like WithField. Strategies generating a single instance use index 0.
+//
+// For example:
+//
+// err := BuildSlice(FactoryModel, 3, WithIndexedField("Position", func(i int) interface{} {
+// 	return i + 1
+// })).To(&modelSlice)
+// // modelSlice[0].Position => 1
+// // modelSlice[2].Position => 3
+//
+func WithIndexedField(name string, value func(i int) interface{}) factoryOption {
+	return func(bp *blueprint) error {
+		if ok := fieldExists(bp.factory.ModelType, name); !ok {
+			return fmt.Errorf(invalidFieldNameErr, name, bp.factory.ModelType.Name())
+		}
+
+		bp.indexedFields = append(bp.indexedFields, &indexedField{name: name, value: value})
+		return nil
+	}
+}
+
+// WithIndexedTraits chooses the traits by the index of the model instance generated by the slice strategies,
+// so that a slice of mixed model instances can be generated at once.
+// The traits are applied after the traits set by WithTraits. Strategies generating a single instance use index 0.
+//
+// For example:
+//
+// err := BuildSlice(UserFactory, 4, WithIndexedTraits(func(i int) []string {
+// 	if i%2 == 0 {
+// 		return []string{"admin"}
+// 	}
+// 	return nil
+// })).To(&users)
+//
+func WithIndexedTraits(traits func(i int) []string) factoryOption {
+	return func(bp *blueprint) error {
+		bp.indexedTraits = append(bp.indexedTraits, traits)
+		return nil
+	}
+}
+
+// WithEach calls each with the index and the pointer of every model instance generated by the slice strategies,
+// after the model instance is generated. Strategies generating a single instance use index 0.
+// It stops generating and returns error if each returns error.
+func WithEach(each func(i int, model interface{}) error) factoryOption {
+	return func(bp *blueprint) error {
+		bp.eachFuncs = append(bp.eachFuncs, each)
+		return nil
+	}
+}
+
+// element returns a copy of the blueprint for the model instance at index i,
+// with the traits and fields set by WithIndexedTraits and WithIndexedField.
+// The sequence fields set by WithSequenceStart start at their starts plus i,
+// so the model instances of a slice use n, n+1, n+2 and so on.
+// It doesn't modify bp, and the copy doesn't share the mutable states with bp.
+func (bp *blueprint) element(i int) (*blueprint, error) {
+	elem := *bp
+	elem.traits = append([]string{}, bp.traits...)
+	elem.filedValues = append([]*FieldDefinition{}, bp.filedValues...)
+	if bp.sequenceStarts != nil {
+		elem.sequenceStarts = make(map[string]int64, len(bp.sequenceStarts))
+		for name, n := range bp.sequenceStarts {
+			elem.sequenceStarts[name] = n + int64(i)
+		}
+	}
+
+	for _, traits := range bp.indexedTraits {
+		if err := WithTraits(traits(i)...)(&elem); err != nil {
+			return nil, fmt.Errorf(invalidElementOptionErr, i, err)
+		}
+	}
+	for _, field := range bp.indexedFields {
+		if err := WithField(field.name, field.value(i))(&elem); err != nil {
+			return nil, fmt.Errorf(invalidElementOptionErr, i, err)
+		}
+	}
+
+	return &elem, nil
+}
+
+// generateElement generates the model instance at index i by generate,
+// and calls the functions set by WithEach with it.
+func (bp *blueprint) generateElement(i int, generate func(elem *blueprint) (interface{}, error)) (interface{}, error) {
+	elem, err := bp.element(i)
+	if err != nil {
+		return nil, err
+	}
+
+	instance, err := generate(elem)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, each := range bp.eachFuncs {
+		if err := each(i, instance); err != nil {
+			return nil, err
+		}
+	}
+	return instance, nil
+}
@@ -0,0 +1,120 @@
+package factory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const (
+	lintFailedErr       = "factory %s%s failed to %s: %v"
+	lintPanicErr        = "panic: %v"
+	lintUndefinedDBErr  = "database connection isn't set, call SetDB before LintCreate"
+	lintTraitNameFormat = " with trait %s"
+	lintTraitsFormat    = " with traits %s"
+)
+
+// Lint builds each factory, each factory with each of its traits, and with each pair of its traits,
+// so the traits conflicting with each other are caught too.
+// It reports every failure by t.Errorf with the factory model type, the trait name and the underlying error,
+// so broken factory definitions can be caught in one dedicated test.
+// It also reports the dynamic fields reading the generated fields which haven't been evaluated,
+// which get zero values from them in the strategies. The generators of dynamic fields may be called more than once to check them,
+// and the random number generator is reseeded for them, so Lint shouldn't run in parallel with the other tests.
+//
+// func TestFactories(t *testing.T) {
+// 	factory.Lint(t, UserFactory, BlogFactory)
+// }
+//
+func Lint(t testing.TB, factories ...*Factory) {
+	t.Helper()
+
+	for _, f := range factories {
+		for _, traits := range lintTraits(f) {
+			err := lint(func() error {
+				bp := newDefaultBlueprint(f)
+				bp.traits = traits
+				bp.checkReads = true
+				_, err := bp.build()
+				return err
+			})
+			if err != nil {
+				t.Errorf(lintFailedErr, f.ModelType.Name(), lintTraitName(traits), StrategyBuild, err)
+			}
+		}
+	}
+}
+
+// LintCreate creates each factory, and each factory with each of its traits and each pair of them like Lint.
+// Each of them is created in a transaction of the database connection set by SetDB,
+// which is rolled back after it's created, so nothing is left in the database.
+func LintCreate(t testing.TB, factories ...*Factory) {
+	t.Helper()
+
+	db := getDB()
+	if db == nil {
+		t.Error(lintUndefinedDBErr)
+		return
+	}
+
+	for _, f := range factories {
+		for _, traits := range lintTraits(f) {
+			err := lint(func() error {
+				tx, err := db.BeginTx(context.Background(), nil)
+				if err != nil {
+					return err
+				}
+				defer tx.Rollback()
+
+				bp := newDefaultBlueprintForCreate(f)
+				bp.traits = traits
+				bp.checkReads = true
+				_, err = bp.create(tx)
+				return err
+			})
+			if err != nil {
+				t.Errorf(lintFailedErr, f.ModelType.Name(), lintTraitName(traits), StrategyCreate, err)
+			}
+		}
+	}
+}
+
+// lintTraits returns the traits used to lint factory f:
+// no trait, each trait, and then each pair of traits, in the order of their names.
+// Larger combinations aren't linted, because their count grows exponentially with the traits.
+func lintTraits(f *Factory) [][]string {
+	names := sortedFieldNames(f.Traits)
+
+	traits := [][]string{nil}
+	for _, trait := range names {
+		traits = append(traits, []string{trait})
+	}
+	for i := range names {
+		for _, other := range names[i+1:] {
+			traits = append(traits, []string{names[i], other})
+		}
+	}
+	return traits
+}
+
+func lintTraitName(traits []string) string {
+	switch len(traits) {
+	case 0:
+		return ""
+	case 1:
+		return fmt.Sprintf(lintTraitNameFormat, traits[0])
+	}
+	return fmt.Sprintf(lintTraitsFormat, strings.Join(traits, ", "))
+}
+
+// lint runs generate, and returns its error or the panic in it as error.
+func lint(generate func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf(lintPanicErr, r)
+		}
+	}()
+
+	return generate()
+}
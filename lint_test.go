@@ -0,0 +1,204 @@
+package factory_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/nauyey/factory"
+	"github.com/nauyey/factory/def"
+	"github.com/nauyey/factory/fake"
+)
+
+// lintRecorder records the errors reported by Lint.
+type lintRecorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *lintRecorder) Helper() {}
+
+func (r *lintRecorder) Error(args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprint(args...))
+}
+
+func (r *lintRecorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestLint(t *testing.T) {
+	userFactory := def.NewFactory(testUser{}, "",
+		def.Field("Name", "test name"),
+		def.Trait("valid",
+			def.Field("Age", int32(16)),
+		),
+		def.Trait("broken",
+			def.DynamicField("NickName", func(model interface{}) (interface{}, error) {
+				return nil, errors.New("broken nick name")
+			}),
+		),
+		def.Trait("panicking",
+			def.AfterBuild(func(model interface{}) error {
+				panic("panicking callback")
+			}),
+		),
+	)
+	blogFactory := def.NewFactory(testBlog{}, "",
+		def.AfterBuild(func(model interface{}) error {
+			return errors.New("broken blog")
+		}),
+	)
+
+	r := &lintRecorder{TB: t}
+	Lint(r, userFactory, blogFactory)
+
+	want := []string{
+		"factory testUser with trait broken failed to build: broken nick name",
+		"factory testUser with trait panicking failed to build: panic: panicking callback",
+		"factory testUser with traits broken, panicking failed to build: broken nick name",
+		"factory testUser with traits broken, valid failed to build: broken nick name",
+		"factory testUser with traits panicking, valid failed to build: panic: panicking callback",
+		"factory testBlog failed to build: broken blog",
+	}
+	if len(r.errors) != len(want) {
+		t.Fatalf("Lint failed with errors %q, want %q", r.errors, want)
+	}
+	for i, err := range r.errors {
+		if !strings.Contains(err, want[i]) {
+			t.Errorf("expects err: \"%s\" contains \"%s\"", err, want[i])
+		}
+	}
+}
+
+func TestLintTraitCombinations(t *testing.T) {
+	userFactory := def.NewFactory(testUser{}, "",
+		def.Field("Name", "test name"),
+		def.Field("Age", int32(30)),
+		def.Trait("chinese",
+			def.Field("Country", "China"),
+		),
+		def.Trait("teenager",
+			def.Field("Age", int32(16)),
+		),
+		def.AfterBuild(func(model interface{}) error {
+			user := model.(*testUser)
+			if user.Country == "China" && user.Age < 18 {
+				return errors.New("conflicting traits")
+			}
+			return nil
+		}),
+	)
+
+	r := &lintRecorder{TB: t}
+	Lint(r, userFactory)
+	want := "factory testUser with traits chinese, teenager failed to build: conflicting traits"
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], want) {
+		t.Errorf("Lint failed with errors %q, want %q", r.errors, want)
+	}
+}
+
+func TestLintDynamicFieldReads(t *testing.T) {
+	count := 0
+	userFactory := def.NewFactory(testUser{}, "",
+		// NickName reads Name before it's evaluated
+		def.DynamicField("NickName", func(model interface{}) (interface{}, error) {
+			return "nick " + model.(*testUser).Name, nil
+		}),
+		def.DynamicField("Country", fake.Name()),
+		def.DynamicField("Age", func(model interface{}) (interface{}, error) {
+			count++
+			return count, nil
+		}),
+		def.DynamicField("Name", func(model interface{}) (interface{}, error) {
+			return "name", nil
+		}),
+		// Now reads Name after it's evaluated
+		def.DynamicField("Now", func(model interface{}) (interface{}, error) {
+			return time.Unix(int64(len(model.(*testUser).Name)), 0), nil
+		}),
+	)
+
+	// the strategies don't check the reads
+	user := &testUser{}
+	if err := Build(userFactory).To(user); err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if user.NickName != "nick " {
+		t.Errorf("Build failed with NickName=%s, want NickName=\"nick \"", user.NickName)
+	}
+
+	r := &lintRecorder{TB: t}
+	Lint(r, userFactory)
+	want := "factory testUser failed to build: dynamic field NickName reads fields which haven't been evaluated: Name,"
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], want) {
+		t.Errorf("Lint failed with errors %q, want %q", r.errors, want)
+	}
+}
+
+func TestLintCreateWithoutDB(t *testing.T) {
+	userFactory := def.NewFactory(testUser{}, "")
+
+	r := &lintRecorder{TB: t}
+	LintCreate(r, userFactory)
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], "database connection isn't set") {
+		t.Errorf("LintCreate without database connection failed with errors %q", r.errors)
+	}
+}
+
+func TestLintCreate(t *testing.T) {
+	type testMember struct {
+		ID   int64  `factory:"id,primary"`
+		Name string `factory:"name"`
+	}
+
+	db, database := openTestDB()
+	defer db.Close()
+	SetDB(db)
+	defer SetDB(nil)
+
+	created := 0
+	memberFactory := def.NewFactory(testMember{}, "members",
+		def.SequenceField("ID", 1, func(n int64) (interface{}, error) {
+			return n, nil
+		}),
+		def.Field("Name", "member name"),
+		def.AfterCreate(func(model interface{}) error {
+			created += len(database.rows("members"))
+			return nil
+		}),
+		def.Trait("named",
+			def.Field("Name", "named member"),
+		),
+		def.Trait("broken",
+			def.AfterCreate(func(model interface{}) error {
+				return errors.New("broken member")
+			}),
+		),
+	)
+
+	r := &lintRecorder{TB: t}
+	LintCreate(r, memberFactory)
+	want := []string{
+		"factory testMember with trait broken failed to create: broken member",
+		"factory testMember with traits broken, named failed to create: broken member",
+	}
+	if len(r.errors) != len(want) {
+		t.Fatalf("LintCreate failed with errors %q, want %q", r.errors, want)
+	}
+	for i, err := range r.errors {
+		if !strings.Contains(err, want[i]) {
+			t.Errorf("expects err: \"%s\" contains \"%s\"", err, want[i])
+		}
+	}
+
+	// the rows are inserted in transactions, which are rolled back,
+	// so each successful creation only sees its own row
+	if created != 2 {
+		t.Errorf("LintCreate failed with %d rows seen by the callbacks, want 2 rows", created)
+	}
+	if rows := database.rows("members"); len(rows) != 0 {
+		t.Errorf("LintCreate failed to roll back rows %v", rows)
+	}
+}
@@ -0,0 +1,141 @@
+package factory
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// The environment variables to configure the sequence partition.
+//
+// FACTORY_SEQUENCE_PARTITION chooses the partition of the current process:
+// "pid" uses a partition chosen by the process id, "random" uses a random partition,
+// and an integer uses the partition of that index.
+// "pid" and "random" only choose from the partitions whose numbers fit in int32, see sequencePartitionCount.
+// The partitions they choose are unique on a best-effort basis, because processes choose them independently:
+// two processes collide if their process ids are equal modulo the partition count, or they draw the same random index.
+// Use integer partitions, like the indexes of CI workers, when the uniqueness must be guaranteed.
+// FACTORY_SEQUENCE_PARTITION_SIZE sets how many numbers each partition has, and it's DefaultSequencePartitionSize by default.
+const (
+	SequencePartitionEnv     = "FACTORY_SEQUENCE_PARTITION"
+	SequencePartitionSizeEnv = "FACTORY_SEQUENCE_PARTITION_SIZE"
+)
+
+// DefaultSequencePartitionSize is the default count of numbers in each sequence partition.
+const DefaultSequencePartitionSize int64 = 1000000
+
+const (
+	invalidSequencePartitionErr     = "invalid sequence partition %s, want \"pid\", \"random\" or a non-negative integer"
+	invalidSequencePartitionSizeErr = "invalid sequence partition size %s, want a positive integer"
+)
+
+var (
+	// sequencePartitionOffset is added to all the numbers of sequences.
+	sequencePartitionOffset int64
+
+	// sequencePartitionErr is the error of the sequence partition configured by environment variables.
+	// It's returned when sequences are used, so a bad configuration doesn't crash the test binary.
+	sequencePartitionErr    error
+	sequencePartitionErrMux sync.Mutex
+)
+
+func init() {
+	setSequencePartitionErr(setSequencePartitionFromEnv())
+}
+
+// SetSequencePartition moves all the sequences into partition index, which contains size numbers.
+// The numbers of sequences are offset by index*size, so the values generated by test binaries
+// running concurrently in different partitions won't collide with each other.
+// The partition is configured by environment variable FACTORY_SEQUENCE_PARTITION by default.
+func SetSequencePartition(index int64, size int64) error {
+	if index < 0 {
+		return fmt.Errorf(invalidSequencePartitionErr, strconv.FormatInt(index, 10))
+	}
+	if size <= 0 {
+		return fmt.Errorf(invalidSequencePartitionSizeErr, strconv.FormatInt(size, 10))
+	}
+
+	atomic.StoreInt64(&sequencePartitionOffset, index*size)
+	setSequencePartitionErr(nil)
+	return nil
+}
+
+func getSequencePartitionOffset() int64 {
+	return atomic.LoadInt64(&sequencePartitionOffset)
+}
+
+func setSequencePartitionErr(err error) {
+	sequencePartitionErrMux.Lock()
+	defer sequencePartitionErrMux.Unlock()
+
+	sequencePartitionErr = err
+}
+
+func getSequencePartitionErr() error {
+	sequencePartitionErrMux.Lock()
+	defer sequencePartitionErrMux.Unlock()
+
+	return sequencePartitionErr
+}
+
+// sequencePartitionCount returns how many partitions of size numbers fit in the range of int32,
+// so the numbers of sequences in them fit in the INT columns of database.
+// It's at least 1.
+func sequencePartitionCount(size int64) int64 {
+	if count := math.MaxInt32/size - 1; count > 1 {
+		return count
+	}
+	return 1
+}
+
+func setSequencePartitionFromEnv() error {
+	partition := os.Getenv(SequencePartitionEnv)
+	if partition == "" {
+		return nil
+	}
+
+	size := DefaultSequencePartitionSize
+	if sizeEnv := os.Getenv(SequencePartitionSizeEnv); sizeEnv != "" {
+		n, err := strconv.ParseInt(sizeEnv, 10, 64)
+		if err != nil || n <= 0 {
+			return fmt.Errorf(invalidSequencePartitionSizeErr, sizeEnv)
+		}
+		size = n
+	}
+
+	index, err := parseSequencePartition(partition, size)
+	if err != nil {
+		return err
+	}
+
+	return SetSequencePartition(index, size)
+}
+
+// parseSequencePartition returns the index of partition, whose size is size.
+// "pid" and "random" choose the index in [0, sequencePartitionCount(size)), and "random" never chooses 0 if possible.
+// There is no shared allocator, so the indexes chosen by different processes may collide.
+func parseSequencePartition(partition string, size int64) (int64, error) {
+	count := sequencePartitionCount(size)
+
+	switch partition {
+	case "pid":
+		return int64(os.Getpid()) % count, nil
+	case "random":
+		if count == 1 {
+			return 0, nil
+		}
+		r := rand.New(rand.NewSource(time.Now().UnixNano() + int64(os.Getpid())))
+		return r.Int63n(count-1) + 1, nil
+	}
+
+	index, err := strconv.ParseInt(partition, 10, 64)
+	if err != nil || index < 0 {
+		return 0, fmt.Errorf(invalidSequencePartitionErr, partition)
+	}
+	return index, nil
+}
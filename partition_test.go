@@ -0,0 +1,98 @@
+package factory
+
+import (
+	"math"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSetSequencePartition(t *testing.T) {
+	defer SetSequencePartition(0, DefaultSequencePartitionSize)
+
+	seq := newSequence(1)
+	if err := SetSequencePartition(3, 1000); err != nil {
+		t.Fatalf("SetSequencePartition failed with error: %v", err)
+	}
+	if n := seq.next(); n != 3001 {
+		t.Errorf("SetSequencePartition failed with n=%d, want n=3001", n)
+	}
+	if n := seq.peek(); n != 3002 {
+		t.Errorf("SetSequencePartition failed with peek n=%d, want n=3002", n)
+	}
+
+	// seed takes the number with the partition offset
+	seq.seed(5000)
+	if n := seq.next(); n != 5000 {
+		t.Errorf("seed in sequence partition failed with n=%d, want n=5000", n)
+	}
+
+	// the numbers of WithSequenceStart aren't offset
+	bp := &blueprint{sequenceStarts: map[string]int64{"ID": 10}}
+	seqValue := newSequenceValue(1, func(n int64) (interface{}, error) {
+		return n, nil
+	})
+	if v, err := bp.sequenceFieldValue("ID", seqValue); err != nil || v != int64(10) {
+		t.Errorf("sequenceFieldValue in sequence partition failed with value=%v, err=%v, want value=10", v, err)
+	}
+
+	if err := SetSequencePartition(-1, 1000); err == nil {
+		t.Error("SetSequencePartition with negative index should return error")
+	}
+	if err := SetSequencePartition(1, 0); err == nil {
+		t.Error("SetSequencePartition with zero size should return error")
+	}
+}
+
+func TestParseSequencePartition(t *testing.T) {
+	count := sequencePartitionCount(DefaultSequencePartitionSize)
+	if count != 2146 {
+		t.Errorf("sequencePartitionCount failed with count=%d, want count=2146", count)
+	}
+	if index, err := parseSequencePartition("pid", DefaultSequencePartitionSize); err != nil || index != int64(os.Getpid())%count {
+		t.Errorf("parseSequencePartition(\"pid\") failed with index=%d, err=%v, want index=%d", index, err, int64(os.Getpid())%count)
+	}
+	for i := 0; i < 10; i++ {
+		index, err := parseSequencePartition("random", DefaultSequencePartitionSize)
+		if err != nil || index <= 0 || index >= count {
+			t.Errorf("parseSequencePartition(\"random\") failed with index=%d, err=%v", index, err)
+		}
+		if offset := index * DefaultSequencePartitionSize; offset+DefaultSequencePartitionSize > math.MaxInt32 {
+			t.Errorf("parseSequencePartition(\"random\") failed with offset=%d overflowing int32", offset)
+		}
+	}
+	if index, err := parseSequencePartition("random", math.MaxInt32); err != nil || index != 0 {
+		t.Errorf("parseSequencePartition(\"random\") with huge size failed with index=%d, err=%v, want index=0", index, err)
+	}
+	if index, err := parseSequencePartition("7", DefaultSequencePartitionSize); err != nil || index != 7 {
+		t.Errorf("parseSequencePartition(\"7\") failed with index=%d, err=%v, want index=7", index, err)
+	}
+	for _, partition := range []string{"-1", "process"} {
+		if _, err := parseSequencePartition(partition, DefaultSequencePartitionSize); err == nil {
+			t.Errorf("parseSequencePartition(%q) should return error", partition)
+		}
+	}
+}
+
+func TestSequencePartitionFromEnv(t *testing.T) {
+	defer SetSequencePartition(0, DefaultSequencePartitionSize)
+
+	t.Setenv(SequencePartitionEnv, "process")
+	setSequencePartitionErr(setSequencePartitionFromEnv())
+
+	// the error is returned when the sequence is used
+	seqValue := newSequenceValue(1, func(n int64) (interface{}, error) {
+		return n, nil
+	})
+	if _, err := seqValue.value(); err == nil || !strings.Contains(err.Error(), "invalid sequence partition process") {
+		t.Errorf("sequence with invalid partition failed with err=%v", err)
+	}
+
+	// SetSequencePartition overrides the invalid configuration
+	if err := SetSequencePartition(0, DefaultSequencePartitionSize); err != nil {
+		t.Fatalf("SetSequencePartition failed with error: %v", err)
+	}
+	if n, err := seqValue.value(); err != nil || n != int64(1) {
+		t.Errorf("sequence failed with n=%v, err=%v, want n=1", n, err)
+	}
+}
@@ -1,6 +1,7 @@
 package factory
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
@@ -12,17 +13,13 @@ import (
 // Parameter db represents the target database connection.
 // Parameter sql and values will conbined to generate a SQL.
 // It returns last insert ID. And it return error if failed to insert data into database.
-func insertRow(db *sql.DB, sql string, values ...interface{}) (int64, error) {
+func insertRow(ctx context.Context, db Executor, sql string, values ...interface{}) (int64, error) {
 	if DebugMode {
 		info.Println("INSERT SQL string: ", sql)
 		info.Println("INSERT SQL arguments: ", values)
 	}
 
-	stmt, err := db.Prepare(sql)
-	if err != nil {
-		return 0, err
-	}
-	res, err := stmt.Exec(values...)
+	res, err := db.ExecContext(ctx, sql, values...)
 	if err != nil {
 		return 0, err
 	}
@@ -34,18 +31,41 @@ func insertRow(db *sql.DB, sql string, values ...interface{}) (int64, error) {
 	return lastID, nil
 }
 
+// deleteRow deletes data from database with sql string and values.
+// It returns error if failed to delete data from database.
+func deleteRow(ctx context.Context, db Executor, sql string, values ...interface{}) error {
+	if DebugMode {
+		info.Println("DELETE SQL string: ", sql)
+		info.Println("DELETE SQL arguments: ", values)
+	}
+
+	_, err := db.ExecContext(ctx, sql, values...)
+	return err
+}
+
 // selectRow queries data from database.
 // db represents the database connection.
 // sql and values are conbined to generate a SQL.
 // selectFieldPointers will store the data scaned from the query result, the *sql.Row instance.
 // It will return errors if failed to query data.
-func selectRow(db *sql.DB, sql string, values []interface{}, selectFieldPointers []interface{}) error {
+func selectRow(ctx context.Context, db Executor, sql string, values []interface{}, selectFieldPointers []interface{}) error {
 	if DebugMode {
 		info.Println("SELECT SQL string: ", sql)
 		info.Println("SELECT SQL arguments: ", values)
 	}
 
-	return db.QueryRow(sql, values...).Scan(selectFieldPointers...)
+	return db.QueryRowContext(ctx, sql, values...).Scan(selectFieldPointers...)
+}
+
+// selectMax queries the maximum value of column in table from database.
+// It returns 0 if there is no rows in the table.
+func selectMax(ctx context.Context, db Executor, table string, column string) (int64, error) {
+	var max sql.NullInt64
+	if err := selectRow(ctx, db, selectMaxSQL(table, column), nil, []interface{}{&max}); err != nil {
+		return 0, err
+	}
+
+	return max.Int64, nil
 }
 
 // insertSQL generates an insert SQL string, like `INSERT INTO table (field1, field2) VALUES (?, ?)`
@@ -66,6 +86,11 @@ func selectSQL(table string, selectFields []string, primaryFields []string) stri
 	return fmt.Sprintf("SELECT %s FROM %s %s", strings.Join(selectFields, ","), table, whereClause(primaryFields))
 }
 
+// selectMaxSQL generates a query SQL string, like `SELECT MAX(column) FROM table`
+func selectMaxSQL(table string, column string) string {
+	return fmt.Sprintf("SELECT MAX(%s) FROM %s", column, table)
+}
+
 // deleteSQL generates a delete SQL.
 func deleteSQL(table string, primaryFields []string) string {
 	return fmt.Sprintf("DELETE FROM %s %s", table, whereClause(primaryFields))
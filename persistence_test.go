@@ -21,6 +21,13 @@ func TestSelectSQL(t *testing.T) {
 	}
 }
 
+func TestSelectMaxSQL(t *testing.T) {
+	sql := selectMaxSQL("test_table", "test_field1")
+	if sql != `SELECT MAX(test_field1) FROM test_table` {
+		t.Errorf("selectMaxSQL failed with sql=%s", sql)
+	}
+}
+
 func TestDeleteSQL(t *testing.T) {
 	table := "test_table"
 	primaryFields := []string{"test_primary_field1"}
@@ -0,0 +1,168 @@
+package factory
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// SeedEnv is the environment variable to set the seed of the random number generator.
+const SeedEnv = "FACTORY_SEED"
+
+const invalidSeedErr = "invalid seed %s, want an integer"
+
+// lockedSource is a rand.Source which is safe for concurrent use.
+type lockedSource struct {
+	seed int64
+	src  rand.Source64
+	mux  sync.Mutex
+}
+
+func (src *lockedSource) Int63() int64 {
+	src.mux.Lock()
+	defer src.mux.Unlock()
+
+	return src.src.Int63()
+}
+
+func (src *lockedSource) Uint64() uint64 {
+	src.mux.Lock()
+	defer src.mux.Unlock()
+
+	return src.src.Uint64()
+}
+
+func (src *lockedSource) Seed(seed int64) {
+	src.mux.Lock()
+	defer src.mux.Unlock()
+
+	src.seed = seed
+	src.src.Seed(seed)
+}
+
+// swap replaces the source with s seeded by seed, and returns the previous seed and source.
+func (src *lockedSource) swap(seed int64, s rand.Source64) (int64, rand.Source64) {
+	src.mux.Lock()
+	defer src.mux.Unlock()
+
+	prevSeed, prevSrc := src.seed, src.src
+	src.seed, src.src = seed, s
+	return prevSeed, prevSrc
+}
+
+func (src *lockedSource) currentSeed() int64 {
+	src.mux.Lock()
+	defer src.mux.Unlock()
+
+	return src.seed
+}
+
+var (
+	randomSource = &lockedSource{src: rand.NewSource(0).(rand.Source64)}
+	random       = rand.New(randomSource)
+
+	// seedErr is the error of the seed set by environment variable FACTORY_SEED.
+	// It's returned by SeedErr and ReportSeed, so a bad seed doesn't crash the test binary.
+	seedErr    error
+	seedErrMux sync.Mutex
+)
+
+func init() {
+	seedFromEnv()
+}
+
+// seedFromEnv seeds the random number generator by environment variable FACTORY_SEED.
+// The current time is used if the seed is invalid, and the error is kept for SeedErr.
+func seedFromEnv() {
+	seed, err := envSeed()
+	if err != nil {
+		seed = time.Now().UnixNano()
+	}
+	Seed(seed)
+	setSeedErr(err)
+}
+
+func setSeedErr(err error) {
+	seedErrMux.Lock()
+	defer seedErrMux.Unlock()
+
+	seedErr = err
+}
+
+// envSeed returns the seed set by environment variable FACTORY_SEED, or the current time by default.
+func envSeed() (int64, error) {
+	seedEnv := os.Getenv(SeedEnv)
+	if seedEnv == "" {
+		return time.Now().UnixNano(), nil
+	}
+
+	seed, err := strconv.ParseInt(seedEnv, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf(invalidSeedErr, seedEnv)
+	}
+	return seed, nil
+}
+
+// Seed seeds the random number generator used by the random helpers, like the generators of package fake.
+// The same seed generates the same data, if the factories are used in the same order.
+// The seed is set by environment variable FACTORY_SEED, or by the current time by default.
+// It clears the error returned by SeedErr.
+func Seed(seed int64) {
+	randomSource.Seed(seed)
+	setSeedErr(nil)
+}
+
+// SeedErr returns the error of environment variable FACTORY_SEED if it isn't an integer.
+// The random number generator is seeded by the current time in this case, until Seed is called.
+func SeedErr() error {
+	seedErrMux.Lock()
+	defer seedErrMux.Unlock()
+
+	return seedErr
+}
+
+// CurrentSeed returns the seed of the random number generator.
+func CurrentSeed() int64 {
+	return randomSource.currentSeed()
+}
+
+// Rand returns the random number generator seeded by Seed.
+// It's safe for concurrent use, except for the method Read.
+// If environment variable FACTORY_SEED is invalid, it's seeded by the current time,
+// and the error is returned by SeedErr.
+func Rand() *rand.Rand {
+	return random
+}
+
+// ReportSeed reseeds the random number generator for the test, and logs the seed when the test fails.
+// The seed is set by environment variable FACTORY_SEED, or by the current time by default.
+// So the data of the test doesn't depend on the other tests,
+// and the test can be replayed with the same data by setting FACTORY_SEED to the logged seed.
+// The previous state of the random number generator is restored when the test finishes.
+// Tests calling ReportSeed shouldn't run in parallel, because they share the random number generator.
+// The test fails if environment variable FACTORY_SEED is invalid.
+//
+// func TestUser(t *testing.T) {
+// 	factory.ReportSeed(t)
+// 	...
+// }
+//
+func ReportSeed(t testing.TB) {
+	seed, err := envSeed()
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+
+	prevSeed, prevSrc := randomSource.swap(seed, rand.NewSource(seed).(rand.Source64))
+	t.Cleanup(func() {
+		if t.Failed() {
+			t.Logf("factory random seed: %d, replay with %s=%d", seed, SeedEnv, seed)
+		}
+		randomSource.swap(prevSeed, prevSrc)
+	})
+}
@@ -0,0 +1,96 @@
+package factory
+
+import "testing"
+
+func TestSeed(t *testing.T) {
+	defer Seed(CurrentSeed())
+
+	Seed(42)
+	if seed := CurrentSeed(); seed != 42 {
+		t.Errorf("Seed failed with seed=%d, want seed=42", seed)
+	}
+	first := []int{Rand().Intn(1000), Rand().Intn(1000), Rand().Intn(1000)}
+
+	Seed(42)
+	for i, n := range first {
+		if replayed := Rand().Intn(1000); replayed != n {
+			t.Errorf("Seed failed with the %dth number=%d, want %d", i, replayed, n)
+		}
+	}
+}
+
+func TestReportSeed(t *testing.T) {
+	defer Seed(CurrentSeed())
+
+	Seed(42)
+	want := []int{Rand().Intn(1000), Rand().Intn(1000), Rand().Intn(1000)}
+
+	// Test ReportSeed reseeds the test from the seed set by environment variable
+	Seed(42)
+	first := Rand().Intn(1000)
+	t.Run("replay", func(t *testing.T) {
+		t.Setenv(SeedEnv, "7")
+		ReportSeed(t)
+
+		if seed := CurrentSeed(); seed != 7 {
+			t.Errorf("ReportSeed failed with seed=%d, want seed=7", seed)
+		}
+		n := Rand().Intn(1000)
+		Seed(7)
+		if replayed := Rand().Intn(1000); replayed != n {
+			t.Errorf("ReportSeed failed with number=%d, want %d", n, replayed)
+		}
+	})
+
+	// Test ReportSeed restores the previous state
+	if seed := CurrentSeed(); seed != 42 {
+		t.Errorf("ReportSeed failed to restore seed=%d, want seed=42", seed)
+	}
+	got := []int{first, Rand().Intn(1000), Rand().Intn(1000)}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ReportSeed failed to restore the %dth number=%d, want %d", i, got[i], want[i])
+		}
+	}
+}
+
+// fatalTB records the calls of Fatal instead of stopping the test.
+type fatalTB struct {
+	testing.TB
+	fatal bool
+}
+
+func (tb *fatalTB) Fatal(args ...interface{}) {
+	tb.fatal = true
+}
+
+func TestSeedErr(t *testing.T) {
+	defer Seed(CurrentSeed())
+
+	// Test a bad FACTORY_SEED is kept as error instead of panicking
+	t.Setenv(SeedEnv, "bad")
+	seedFromEnv()
+	if err := SeedErr(); err == nil {
+		t.Error("SeedErr with bad FACTORY_SEED should return error")
+	}
+	Rand().Intn(1000)
+
+	// Test ReportSeed fails the test with bad FACTORY_SEED
+	tb := &fatalTB{TB: t}
+	ReportSeed(tb)
+	if !tb.fatal {
+		t.Error("ReportSeed with bad FACTORY_SEED should fail the test")
+	}
+
+	// Test Seed clears the error
+	Seed(42)
+	if err := SeedErr(); err != nil {
+		t.Errorf("Seed failed to clear error: %v", err)
+	}
+
+	t.Setenv(SeedEnv, "7")
+	seedFromEnv()
+	if err := SeedErr(); err != nil || CurrentSeed() != 7 {
+		t.Errorf("seedFromEnv failed with seed=%d, error=%v, want seed=7", CurrentSeed(), err)
+	}
+}
@@ -0,0 +1,144 @@
+package factory
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+const (
+	duplicateFactoryNameErr = "duplicate registration of factory %s"
+	undefinedFactoryNameErr = "undefined factory %s"
+	invalidFactoryNameErr   = "invalid factory name %q"
+)
+
+// Registry represents a set of factories and named sequences registered by unique names.
+// It allows tooling to enumerate factories and to look them up by names.
+// Different packages can use isolated registries created by NewRegistry.
+type Registry struct {
+	factories map[string]*Factory
+	sequences map[string]*sequenceValue
+	mux       sync.RWMutex
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		factories: map[string]*Factory{},
+		sequences: map[string]*sequenceValue{},
+	}
+}
+
+// DefaultRegistry is the registry used by Register, Lookup and def.Register.
+var DefaultRegistry = NewRegistry()
+
+// Register registers factory f by name.
+// It returns error if the name is empty or another factory has been registered by the same name.
+func (r *Registry) Register(name string, f *Factory) error {
+	if name == "" {
+		return fmt.Errorf(invalidFactoryNameErr, name)
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if _, ok := r.factories[name]; ok {
+		return fmt.Errorf(duplicateFactoryNameErr, name)
+	}
+	r.factories[name] = f
+	return nil
+}
+
+// Lookup returns the factory registered by name.
+func (r *Registry) Lookup(name string) (*Factory, bool) {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	f, ok := r.factories[name]
+	return f, ok
+}
+
+// Names returns the names of all the registered factories in order.
+func (r *Registry) Names() []string {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Factories returns all the registered factories in the order of their names.
+func (r *Registry) Factories() []*Factory {
+	names := r.Names()
+
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	factories := make([]*Factory, len(names))
+	for i, name := range names {
+		factories[i] = r.factories[name]
+	}
+	return factories
+}
+
+// Build builds an instance from the factory registered by name like function Build.
+// The To method returns error if the factory isn't registered.
+func (r *Registry) Build(name string, opts ...factoryOption) to {
+	f, err := r.lookupFactory(name)
+	if err != nil {
+		return &errorTo{err: err}
+	}
+	return Build(f, opts...)
+}
+
+// BuildSlice builds a slice of instances from the factory registered by name like function BuildSlice.
+// The To method returns error if the factory isn't registered.
+func (r *Registry) BuildSlice(name string, count int, opts ...factoryOption) to {
+	f, err := r.lookupFactory(name)
+	if err != nil {
+		return &errorTo{err: err}
+	}
+	return BuildSlice(f, count, opts...)
+}
+
+// Create creates an instance from the factory registered by name like function Create.
+// The To method returns error if the factory isn't registered.
+func (r *Registry) Create(name string, opts ...factoryOption) to {
+	f, err := r.lookupFactory(name)
+	if err != nil {
+		return &errorTo{err: err}
+	}
+	return Create(f, opts...)
+}
+
+// CreateSlice creates a slice of instances from the factory registered by name like function CreateSlice.
+// The To method returns error if the factory isn't registered.
+func (r *Registry) CreateSlice(name string, count int, opts ...factoryOption) to {
+	f, err := r.lookupFactory(name)
+	if err != nil {
+		return &errorTo{err: err}
+	}
+	return CreateSlice(f, count, opts...)
+}
+
+func (r *Registry) lookupFactory(name string) (*Factory, error) {
+	f, ok := r.Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf(undefinedFactoryNameErr, name)
+	}
+	return f, nil
+}
+
+// Register registers factory f by name in DefaultRegistry.
+func Register(name string, f *Factory) error {
+	return DefaultRegistry.Register(name, f)
+}
+
+// Lookup returns the factory registered by name in DefaultRegistry.
+func Lookup(name string) (*Factory, bool) {
+	return DefaultRegistry.Lookup(name)
+}
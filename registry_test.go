@@ -0,0 +1,64 @@
+package factory_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/nauyey/factory"
+	"github.com/nauyey/factory/def"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	userFactory := def.RegisterIn(r, "user", def.NewFactory(testUser{}, "",
+		def.Field("Name", "test name"),
+	))
+	blogFactory := def.RegisterIn(r, "blog", def.NewFactory(testBlog{}, ""))
+
+	// Test Lookup
+	if f, ok := r.Lookup("user"); !ok || f != userFactory {
+		t.Errorf("Lookup failed with factory=%v, ok=%v, want the user factory", f, ok)
+	}
+	if _, ok := r.Lookup("comment"); ok {
+		t.Errorf("Lookup of unregistered factory should fail")
+	}
+
+	// Test the registry is isolated from DefaultRegistry
+	if _, ok := Lookup("user"); ok {
+		t.Errorf("Lookup in DefaultRegistry should fail for the factory registered in another registry")
+	}
+
+	// Test Names and Factories
+	if names := r.Names(); strings.Join(names, ",") != "blog,user" {
+		t.Errorf("Names failed with names=%v, want [blog user]", names)
+	}
+	if factories := r.Factories(); len(factories) != 2 || factories[0] != blogFactory || factories[1] != userFactory {
+		t.Errorf("Factories failed with factories=%v", factories)
+	}
+
+	// Test duplicate registration
+	if err := r.Register("user", userFactory); err == nil {
+		t.Errorf("Register with duplicate name should return error")
+	}
+
+	// Test Build by name
+	user := &testUser{}
+	if err := r.Build("user", WithField("NickName", "nick")).To(user); err != nil {
+		t.Fatalf("Build by name failed with error: %v", err)
+	}
+	if user.Name != "test name" || user.NickName != "nick" {
+		t.Errorf("Build by name failed with Name=%s, NickName=%s", user.Name, user.NickName)
+	}
+
+	users := []*testUser{}
+	if err := r.BuildSlice("user", 2).To(&users); err != nil {
+		t.Fatalf("BuildSlice by name failed with error: %v", err)
+	}
+	if len(users) != 2 {
+		t.Errorf("BuildSlice by name failed with len=%d, want len=2", len(users))
+	}
+
+	if err := r.Build("comment").To(user); err == nil || !strings.Contains(err.Error(), "undefined factory comment") {
+		t.Errorf("Build by unregistered name failed with error: %v", err)
+	}
+}
@@ -1,47 +1,219 @@
 package factory
 
 import (
+	"fmt"
 	"sync"
 )
 
+const (
+	undefinedSequenceErr = "undefined sequence %s"
+	duplicateSequenceErr = "duplicate definition of sequence %s"
+)
+
+var (
+	allSequences    []*sequence
+	allSequencesMux sync.Mutex
+)
+
+// DefineSequence defines a named sequence in DefaultRegistry, which can be shared by the sequence fields of any factories.
+// Every value generated from the sequence uses a unique number, no matter which factory generates it.
+// It returns error if a sequence with the same name has been defined.
+func DefineSequence(name string, first int64, value SequenceFieldValue) error {
+	return DefaultRegistry.DefineSequence(name, first, value)
+}
+
+// DefineSequence defines a named sequence in the registry like function DefineSequence.
+// The names of sequences in different registries don't conflict.
+func (r *Registry) DefineSequence(name string, first int64, value SequenceFieldValue) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if _, ok := r.sequences[name]; ok {
+		return fmt.Errorf(duplicateSequenceErr, name)
+	}
+	r.sequences[name] = newSequenceValue(first, value)
+
+	return nil
+}
+
+// ResetAllSequences rewinds all the sequences to their start numbers,
+// including the sequences of all factories, registered or not, and the named sequences of all registries.
+func ResetAllSequences() {
+	allSequencesMux.Lock()
+	defer allSequencesMux.Unlock()
+
+	for _, seq := range allSequences {
+		seq.rewind()
+	}
+}
+
+// ResetSequences rewinds the sequences of the registry to their start numbers,
+// including the sequences used by the registered factories and the named sequences.
+func (r *Registry) ResetSequences() {
+	ResetSequences(r.Factories()...)
+
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	for _, seqValue := range r.sequences {
+		seqValue.sequence.rewind()
+	}
+}
+
+// ResetSequences rewinds the sequences used by the factories to their start numbers,
+// including the sequences defined in their traits and associations.
+// The named sequences referenced by the factories are rewound too.
+func ResetSequences(factories ...*Factory) {
+	for _, f := range factories {
+		for _, seqValue := range factorySequenceValues(f) {
+			seqValue.sequence.rewind()
+		}
+	}
+}
+
+// PeekSequence returns the number which will be used by the next value of sequence field name in factory f.
+// It returns error if the field isn't a sequence field of the factory.
+func PeekSequence(f *Factory, name string) (int64, error) {
+	fieldValue, _ := f.FieldValue(name)
+	seqValue, ok := fieldValue.(*sequenceValue)
+	if !ok {
+		return 0, fmt.Errorf(undefinedSequenceFieldErr, name, f.ModelType.Name())
+	}
+
+	return seqValue.sequence.peek(), nil
+}
+
+// factorySequenceValues collects the sequence field values defined in the factory,
+// its traits and its associations.
+func factorySequenceValues(f *Factory) []*sequenceValue {
+	var seqValues []*sequenceValue
+
+	factories := []*Factory{f}
+	for _, trait := range sortedFieldNames(f.Traits) {
+		factories = append(factories, f.Traits[trait])
+	}
+
+	for _, definedFactory := range factories {
+		for _, field := range definedFactory.Fields {
+			switch fieldValue := field.Value.(type) {
+			case *sequenceValue:
+				seqValues = append(seqValues, fieldValue)
+			case *AssociationFieldValue:
+				seqValues = append(seqValues, associationSequenceValues(fieldValue.OriginalFactory, fieldValue.Factory)...)
+			case *ManyToManyFieldValue:
+				seqValues = append(seqValues, associationSequenceValues(fieldValue.OriginalFactory, fieldValue.Factory)...)
+			}
+		}
+	}
+
+	return seqValues
+}
+
+// associationSequenceValues collects the sequence field values of the association factories.
+// originalFactory may be nil for polymorphic associations.
+func associationSequenceValues(originalFactory *Factory, f *Factory) []*sequenceValue {
+	var seqValues []*sequenceValue
+	if originalFactory != nil {
+		seqValues = factorySequenceValues(originalFactory)
+	}
+	return append(seqValues, factorySequenceValues(f)...)
+}
+
+func registerSequence(seq *sequence) {
+	allSequencesMux.Lock()
+	defer allSequencesMux.Unlock()
+
+	allSequences = append(allSequences, seq)
+}
+
+func (r *Registry) lookupSequence(name string) (*sequenceValue, bool) {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	seqValue, ok := r.sequences[name]
+	return seqValue, ok
+}
+
 type sequence struct {
-	first        int64
-	value        int64
-	roundStarted bool
-	mux          sync.Mutex
+	first int64
+	// value is the number which will be used by the next call of next,
+	// before it's offset by the sequence partition
+	value int64
+	// seeded records the keys of the maximums which the sequence has been seeded above
+	seeded map[string]bool
+	mux    sync.Mutex
+}
+
+// newSequence creates a sequence starting at first.
+// The sequence is registered to be rewound by ResetAllSequences.
+func newSequence(first int64) *sequence {
+	seq := &sequence{
+		first: first,
+		value: first,
+	}
+	registerSequence(seq)
+	return seq
 }
 
-// peek returns the current cursor number of the sequence
+// peek returns the number which will be used by the next call of next.
+// The numbers of sequences are offset by the sequence partition.
 func (seq *sequence) peek() int64 {
 	seq.mux.Lock()
 	defer seq.mux.Unlock()
 
-	if seq.value < seq.first {
-		return seq.first
-	}
-	return seq.value
+	return seq.value + getSequencePartitionOffset()
 }
 
-// next move the cursor of the sequence to next number
+// next returns the number of the cursor and moves the cursor of the sequence to next number
 func (seq *sequence) next() int64 {
 	seq.mux.Lock()
 	defer seq.mux.Unlock()
-	if seq.value < seq.first || (seq.value == seq.first && !seq.roundStarted) {
-		seq.value = seq.first
-		seq.roundStarted = true
-	} else {
-		seq.value = seq.value + 1
-	}
 
-	return seq.value
+	n := seq.value
+	seq.value = seq.value + 1
+
+	return n + getSequencePartitionOffset()
 }
 
 // rewind moves the cursor of the sequence to the start number of the sequence
 func (seq *sequence) rewind() {
 	seq.mux.Lock()
 	defer seq.mux.Unlock()
+
 	seq.value = seq.first
-	seq.roundStarted = false
+	seq.seeded = nil
+}
+
+// seed moves the cursor of the sequence to number n.
+// n is the number returned by next, so it isn't offset by the sequence partition.
+func (seq *sequence) seed(n int64) {
+	seq.mux.Lock()
+	defer seq.mux.Unlock()
+
+	seq.value = n - getSequencePartitionOffset()
+}
+
+// seedAbove moves the cursor of the sequence above the maximum number returned by max if the cursor isn't.
+// The maximum of key is only seeded once until the sequence is rewound.
+func (seq *sequence) seedAbove(key string, max func() (int64, error)) error {
+	seq.mux.Lock()
+	defer seq.mux.Unlock()
+
+	if seq.seeded[key] {
+		return nil
+	}
 
-	return
+	n, err := max()
+	if err != nil {
+		return err
+	}
+	if offset := getSequencePartitionOffset(); seq.value+offset <= n {
+		seq.value = n + 1 - offset
+	}
+
+	if seq.seeded == nil {
+		seq.seeded = map[string]bool{}
+	}
+	seq.seeded[key] = true
+	return nil
 }
@@ -0,0 +1,45 @@
+package factory
+
+import "testing"
+
+func TestSequenceSeedAbove(t *testing.T) {
+	seq := newSequence(1)
+	queries := 0
+	max := func() (int64, error) {
+		queries++
+		return 100, nil
+	}
+
+	if err := seq.seedAbove("test_table.id", max); err != nil {
+		t.Fatalf("seedAbove failed with error: %v", err)
+	}
+	if n := seq.next(); n != 101 {
+		t.Errorf("seedAbove failed with n=%d, want n=101", n)
+	}
+
+	// the maximum of the same key is queried only once
+	if err := seq.seedAbove("test_table.id", max); err != nil {
+		t.Fatalf("seedAbove failed with error: %v", err)
+	}
+	if queries != 1 {
+		t.Errorf("seedAbove queried %d times, want 1 time", queries)
+	}
+
+	// the cursor isn't moved back by a smaller maximum
+	seq.seed(200)
+	if err := seq.seedAbove("other_table.id", max); err != nil {
+		t.Fatalf("seedAbove failed with error: %v", err)
+	}
+	if n := seq.peek(); n != 200 {
+		t.Errorf("seedAbove failed with n=%d, want n=200", n)
+	}
+
+	// the maximum is queried again after rewind
+	seq.rewind()
+	if err := seq.seedAbove("test_table.id", max); err != nil {
+		t.Fatalf("seedAbove failed with error: %v", err)
+	}
+	if queries != 3 || seq.peek() != 101 {
+		t.Errorf("seedAbove after rewind failed with queries=%d, n=%d, want queries=3, n=101", queries, seq.peek())
+	}
+}
@@ -1,20 +1,28 @@
 package factory
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+
+	"github.com/nauyey/factory/utils"
 )
 
 const (
 	invalidFieldNameErr      = "invalid field name %s to define factory of %s"
 	invalidFieldValueTypeErr = "cannot use value (type %v) as type %v of field %s to define factory of %s"
 	undefinedTraitErr        = "undefined trait name %s of type %s factory"
+	undefinedAssociationErr  = "undefined association %s of type %s factory"
+	invalidAssociationErr    = "cannot use factory of type %s as association %s of type %s factory"
+	invalidStrategyErr       = "invalid strategy %s of association %s"
+	undefinedTransientErr    = "undefined transient field %s of type %s factory"
+	invalidTransientTypeErr  = "cannot use value (type %v) as type %v of transient field %s of type %s factory"
+	invalidStructTypeErr     = "cannot use struct (type %v) as type %s to override fields"
 )
 
 func newDefaultBlueprint(f *Factory) *blueprint {
 	return &blueprint{
-		factory:     f,
-		filedValues: map[string]interface{}{},
+		factory: f,
 	}
 }
 
@@ -34,6 +42,10 @@ func newDefaultBlueprintForDelete(f *Factory) *blueprint {
 
 type factoryOption func(*blueprint) error
 
+// Option is the type of the options passed to the strategies, like WithTraits and WithField.
+// It allows other packages to wrap the strategies.
+type Option = factoryOption
+
 // WithTraits defines which traits the new instance will use.
 // It can take multiple traits. These traits will be executed one by one.
 // So the later one may override the one before.
@@ -56,6 +68,9 @@ func WithTraits(traits ...string) factoryOption {
 
 // WithField sets the value of a specific field.
 // This way has the highest priority to set the field value.
+// The value is accepted if it's assignable or safely convertible to the type of the field,
+// like 30 for an int32 field, "active" for a field of type Status string, nil for a pointer field,
+// and "name" for a *string field.
 func WithField(name string, value interface{}) factoryOption {
 	return func(bp *blueprint) error {
 		modelTypeName := bp.factory.ModelType.Name()
@@ -64,11 +79,344 @@ func WithField(name string, value interface{}) factoryOption {
 		}
 
 		field, _ := structFieldByName(bp.factory.ModelType, name)
-		if valueType := reflect.TypeOf(value); valueType != field.Type {
-			return fmt.Errorf(invalidFieldValueTypeErr, valueType, field.Type, name, modelTypeName)
+		if _, err := utils.ConvertValue(value, field.Type); err != nil {
+			return fmt.Errorf(invalidFieldValueTypeErr, reflect.TypeOf(value), field.Type, name, modelTypeName)
 		}
 
-		bp.filedValues[name] = value
+		bp.filedValues = append(bp.filedValues, &FieldDefinition{Name: name, Value: value})
+		return nil
+	}
+}
+
+// WithoutField leaves the field as zero value, no matter how it's defined.
+// The sequence of the field isn't consumed, and the generator of the field isn't evaluated.
+func WithoutField(name string) factoryOption {
+	return func(bp *blueprint) error {
+		if ok := fieldExists(bp.factory.ModelType, name); !ok {
+			return fmt.Errorf(invalidFieldNameErr, name, bp.factory.ModelType.Name())
+		}
+
+		bp.filedValues = append(bp.filedValues, &FieldDefinition{Name: name, Value: skippedFieldValue{}})
+		return nil
+	}
+}
+
+// WithFields sets the values of fields by names, like calling WithField for each of them.
+// The fields are set in the order of their names.
+func WithFields(values map[string]interface{}) factoryOption {
+	return func(bp *blueprint) error {
+		for _, name := range sortedFieldNames(values) {
+			if err := WithField(name, values[name])(bp); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// WithStruct sets the values of fields from the model struct instance v (or pointer of it),
+// like calling WithField for each of them.
+// Without fields, all the non-zero fields of v are set. Otherwise, only the fields named by fields are set,
+// even if they are zero, which allows to override fields with zero values.
+//
+// err := Build(FactoryModel, WithStruct(Model{Name: "new name", Age: 3})).To(model)
+// err := Build(FactoryModel, WithStruct(Model{Name: "new name"}, "Name", "Age")).To(model) // model.Age => 0
+//
+func WithStruct(v interface{}, fields ...string) factoryOption {
+	return func(bp *blueprint) error {
+		value := reflect.ValueOf(v)
+		if value.Kind() == reflect.Ptr && !value.IsNil() {
+			value = value.Elem()
+		}
+		if !value.IsValid() || value.Type() != bp.factory.ModelType {
+			return fmt.Errorf(invalidStructTypeErr, reflect.TypeOf(v), bp.factory.ModelType.Name())
+		}
+
+		if len(fields) > 0 {
+			for _, name := range fields {
+				if ok := fieldExists(bp.factory.ModelType, name); !ok {
+					return fmt.Errorf(invalidFieldNameErr, name, bp.factory.ModelType.Name())
+				}
+				if err := WithField(name, instanceFieldValue(value, name))(bp); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			if field.PkgPath != "" || value.Field(i).IsZero() {
+				continue
+			}
+			if err := WithField(field.Name, value.Field(i).Interface())(bp); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// WithTransient sets the value of a transient field defined by def.Transient.
+// The value can be read by the Evaluator in DependentFieldValue and EvaluatorCallback functions.
+// It's converted to the type of the default value of the transient field, like WithField.
+// It returns error if the value can't be converted.
+func WithTransient(name string, value interface{}) factoryOption {
+	return func(bp *blueprint) error {
+		modelTypeName := bp.factory.ModelType.Name()
+		defaultValue, ok := definedTransient(bp.factory, name)
+		if !ok {
+			return fmt.Errorf(undefinedTransientErr, name, modelTypeName)
+		}
+
+		// the value is converted to the type of the default value like WithField,
+		// so untyped constants can be used
+		if defaultValue != nil {
+			converted, err := utils.ConvertValue(value, reflect.TypeOf(defaultValue))
+			if err != nil {
+				return fmt.Errorf(invalidTransientTypeErr, reflect.TypeOf(value), reflect.TypeOf(defaultValue), name, modelTypeName)
+			}
+			value = converted
+		}
+
+		if bp.transients == nil {
+			bp.transients = map[string]interface{}{}
+		}
+		bp.transients[name] = value
+		return nil
+	}
+}
+
+// AssociationOption is the type of the options passed to WithAssociation.
+// A *Factory chooses the factory of the association,
+// and the factory options, like WithTraits and WithField, override the association when it's generated.
+type AssociationOption interface {
+	applyAssociationOption(override *associationOverride)
+}
+
+func (f *Factory) applyAssociationOption(override *associationOverride) {
+	override.factory = f
+}
+
+func (opt factoryOption) applyAssociationOption(override *associationOverride) {
+	override.options = append(override.options, opt)
+}
+
+// WithAssociation overrides the association field value by options.
+// A *Factory option chooses the factory from which the association field value is generated.
+// It's mostly used by polymorphic associations, which set the type field and the reference field
+// from whichever factory is chosen.
+// The other options are passed to the association before it's built or created,
+// so that the fields saved into database are the same as the association instance.
+//
+// For example:
+//
+// err := Build(CommentFactory, WithAssociation("Commentable", PostFactory)).To(comment)
+// // comment.CommentableType => "Post"
+// // comment.CommentableID => comment.Commentable.(*Post).ID
+//
+// err := Create(BlogFactory, WithAssociation("Author", WithTraits("admin"), WithField("Name", "Tony"))).To(blog)
+// // blog.Author.Name => "Tony"
+//
+func WithAssociation(name string, opts ...AssociationOption) factoryOption {
+	return func(bp *blueprint) error {
+		modelTypeName := bp.factory.ModelType.Name()
+		associationFieldValue, ok := definedAssociationFieldValue(bp.factory, name)
+		if !ok {
+			return fmt.Errorf(undefinedAssociationErr, name, modelTypeName)
+		}
+
+		override := bp.associationOverride(name)
+		for _, opt := range opts {
+			opt.applyAssociationOption(override)
+		}
+
+		associationFactory := associationFieldValue.OriginalFactory
+		if override.factory != nil {
+			if associationFieldValue.TypeField == "" && associationFactory.ModelType != override.factory.ModelType {
+				return fmt.Errorf(invalidAssociationErr, override.factory.ModelType.Name(), name, modelTypeName)
+			}
+			if err := checkAssociationFactory(bp.factory, name, associationFieldValue, override.factory); err != nil {
+				return err
+			}
+			associationFactory = override.factory
+		}
+
+		// check the options against the association factory before generating anything
+		if associationFactory != nil {
+			if err := applyFactoryOptions(newDefaultBlueprint(associationFactory), override.options); err != nil {
+				return fmt.Errorf(invalidAssociationOptionErr, name, err)
+			}
+		}
+		return nil
+	}
+}
+
+// checkAssociationFactory checks the association name of factory f can be generated from associationFactory.
+// The association field must accept the model instances of associationFactory,
+// and the reference field must accept the values of their association reference field.
+func checkAssociationFactory(f *Factory, name string, associationFieldValue *AssociationFieldValue, associationFactory *Factory) error {
+	modelTypeName := f.ModelType.Name()
+	associationType := associationFactory.ModelType
+
+	field, _ := structFieldByName(f.ModelType, name)
+	if !utils.ConvertibleType(reflect.PtrTo(associationType), field.Type) {
+		return fmt.Errorf(invalidAssociationErr, associationType.Name(), name, modelTypeName)
+	}
+
+	associationReferenceField, ok := associationType.FieldByName(associationFieldValue.AssociationReferenceField)
+	if !ok {
+		return fmt.Errorf(invalidFieldNameErr, associationFieldValue.AssociationReferenceField, associationType.Name())
+	}
+
+	referenceField, _ := structFieldByName(f.ModelType, associationFieldValue.ReferenceField)
+	if !utils.ConvertibleType(associationReferenceField.Type, referenceField.Type) {
+		return fmt.Errorf(invalidFieldValueTypeErr, associationReferenceField.Type, referenceField.Type, associationFieldValue.ReferenceField, modelTypeName)
+	}
+	return nil
+}
+
+// WithAssociationInstance refers the association to an existing instance, which is usually created before.
+// The association won't be built or created. The association field is set as instance,
+// and the reference field is set by the instance.
+//
+// For example:
+//
+// err := Create(BlogFactory, WithAssociationInstance("Author", author)).To(blog)
+// // blog.Author => author
+// // blog.AuthorID => author.ID
+//
+func WithAssociationInstance(name string, instance interface{}) factoryOption {
+	return func(bp *blueprint) error {
+		modelTypeName := bp.factory.ModelType.Name()
+		associationFieldValue, ok := definedAssociationFieldValue(bp.factory, name)
+		if !ok {
+			return fmt.Errorf(undefinedAssociationErr, name, modelTypeName)
+		}
+
+		field, _ := structFieldByName(bp.factory.ModelType, name)
+		fieldValue, err := utils.ConvertValue(instance, field.Type)
+		if err != nil || instance == nil {
+			return fmt.Errorf(invalidFieldValueTypeErr, reflect.TypeOf(instance), field.Type, name, modelTypeName)
+		}
+
+		value := reflect.Indirect(reflect.ValueOf(fieldValue))
+		if value.Kind() != reflect.Struct {
+			return fmt.Errorf(invalidFieldValueTypeErr, reflect.TypeOf(instance), field.Type, name, modelTypeName)
+		}
+		referenceValue := value.FieldByName(associationFieldValue.AssociationReferenceField)
+		if !referenceValue.IsValid() {
+			return fmt.Errorf(invalidFieldNameErr, associationFieldValue.AssociationReferenceField, value.Type().Name())
+		}
+
+		referenceField, _ := structFieldByName(bp.factory.ModelType, associationFieldValue.ReferenceField)
+		id, err := utils.ConvertValue(referenceValue.Interface(), referenceField.Type)
+		if err != nil {
+			return fmt.Errorf(invalidFieldValueTypeErr, referenceValue.Type(), referenceField.Type, associationFieldValue.ReferenceField, modelTypeName)
+		}
+
+		override := bp.associationOverride(name)
+		override.instance = fieldValue
+		override.id = id
+		return nil
+	}
+}
+
+// WithAssociationStrategy sets the strategy to generate the association field value.
+// It overrides the strategy defined in the association definition.
+// With StrategyBuild, the association won't be saved into database even if the instance is created.
+func WithAssociationStrategy(name string, strategy string) factoryOption {
+	return func(bp *blueprint) error {
+		if _, ok := definedAssociationFieldValue(bp.factory, name); !ok {
+			return fmt.Errorf(undefinedAssociationErr, name, bp.factory.ModelType.Name())
+		}
+
+		if strategy != StrategyBuild && strategy != StrategyCreate {
+			return fmt.Errorf(invalidStrategyErr, strategy, name)
+		}
+
+		bp.associationOverride(name).strategy = strategy
+		return nil
+	}
+}
+
+// WithAssociationID refers the association to an existing record by id.
+// The association won't be built or created. Only the reference field will be set as id.
+// The type field of polymorphic association is set by the factory chosen by WithAssociation or the default factory,
+// and the generation returns error if there is neither of them.
+func WithAssociationID(name string, id interface{}) factoryOption {
+	return func(bp *blueprint) error {
+		modelTypeName := bp.factory.ModelType.Name()
+		associationFieldValue, ok := definedAssociationFieldValue(bp.factory, name)
+		if !ok {
+			return fmt.Errorf(undefinedAssociationErr, name, modelTypeName)
+		}
+
+		field, _ := structFieldByName(bp.factory.ModelType, associationFieldValue.ReferenceField)
+		idValue, err := utils.ConvertValue(id, field.Type)
+		if err != nil {
+			return fmt.Errorf(invalidFieldValueTypeErr, reflect.TypeOf(id), field.Type, associationFieldValue.ReferenceField, modelTypeName)
+		}
+
+		override := bp.associationOverride(name)
+		override.id = idValue
+		override.hasID = true
+		return nil
+	}
+}
+
+// WithoutAssociation generates no association.
+// The association field and the reference field, and the type field of polymorphic association, are left as zero values.
+func WithoutAssociation(name string) factoryOption {
+	return func(bp *blueprint) error {
+		if _, ok := definedAssociationFieldValue(bp.factory, name); !ok {
+			return fmt.Errorf(undefinedAssociationErr, name, bp.factory.ModelType.Name())
+		}
+
+		bp.associationOverride(name).skipped = true
+		return nil
+	}
+}
+
+// WithSequenceStart generates the sequence field name from number n in this call.
+// The instances generated by BuildSlice and CreateSlice use n, n+1, n+2 and so on.
+// The sequence of the factory isn't moved, so the later calls without it continue the sequence as usual.
+// n isn't offset by the sequence partition, like the numbers returned by PeekSequence,
+// so the caller chooses the exact numbers, and is responsible to keep them apart from the other processes.
+// It returns error if the field isn't a sequence field of the factory or the traits in use.
+func WithSequenceStart(name string, n int64) factoryOption {
+	return func(bp *blueprint) error {
+		if ok := fieldExists(bp.factory.ModelType, name); !ok {
+			return fmt.Errorf(invalidFieldNameErr, name, bp.factory.ModelType.Name())
+		}
+
+		if bp.sequenceStarts == nil {
+			bp.sequenceStarts = map[string]int64{}
+		}
+		bp.sequenceStarts[name] = n
+		return nil
+	}
+}
+
+// WithContext sets the context of the SQL statements executed by the strategies,
+// which can be read by the callbacks from Evaluator.Context.
+func WithContext(ctx context.Context) factoryOption {
+	return func(bp *blueprint) error {
+		bp.ctx = ctx
+		return nil
+	}
+}
+
+// WithExecutor sets the database executor, like a *sql.Tx, to save the instance and its associations,
+// instead of the database connection set by SetDB. The callbacks can read it from Evaluator.Executor,
+// so that they can save related records in the same transaction.
+//
+// tx, err := db.Begin()
+// err = Create(FactoryModel, WithExecutor(tx)).To(model)
+//
+func WithExecutor(db Executor) factoryOption {
+	return func(bp *blueprint) error {
+		bp.executor = db
 		return nil
 	}
 }
@@ -87,12 +435,11 @@ func WithField(name string, value interface{}) factoryOption {
 func Build(f *Factory, opts ...factoryOption) to {
 	bp := newDefaultBlueprint(f)
 
-	for _, opt := range opts {
-		opt(bp)
-	}
+	err := applyFactoryOptions(bp, opts)
 
 	return &buildTo{
 		blueprint: bp,
+		err:       err,
 	}
 }
 
@@ -109,13 +456,56 @@ func Build(f *Factory, opts ...factoryOption) to {
 func BuildSlice(f *Factory, count int, opts ...factoryOption) to {
 	bp := newDefaultBlueprint(f)
 
-	for _, opt := range opts {
-		opt(bp)
-	}
+	err := applyFactoryOptions(bp, opts)
 
 	return &buildSliceTo{
 		blueprint: bp,
 		count:     count,
+		err:       err,
+	}
+}
+
+// BuildStubbed creates an instance from a factory as if it has been stored into database,
+// but won't touch database, so SetDB isn't needed.
+// The primary key fields, declared by tag `factory:",primary"`, are set with unique fake ids
+// if they aren't set by the factory. All the associations are stubbed too,
+// and the reference fields are set with their fake ids.
+// Callbacks AfterStub are executed instead of AfterBuild.
+//
+// model := &Model{}
+//
+// err := BuildStubbed(FactoryModel,
+// 	WithTraits("Chinese"),
+// 	WithField("Name", "new name"),
+// ).To(model)
+// // model.ID => 1001
+//
+func BuildStubbed(f *Factory, opts ...factoryOption) to {
+	bp := newDefaultBlueprint(f)
+
+	err := applyFactoryOptions(bp, opts)
+
+	return &stubTo{
+		blueprint: bp,
+		err:       err,
+	}
+}
+
+// BuildStubbedSlice creates a slice of instances from a factory like BuildStubbed.
+//
+// modelSlice := []*Model{}
+//
+// err := BuildStubbedSlice(FactoryModel, 3).To(&modelSlice)
+//
+func BuildStubbedSlice(f *Factory, count int, opts ...factoryOption) to {
+	bp := newDefaultBlueprint(f)
+
+	err := applyFactoryOptions(bp, opts)
+
+	return &stubSliceTo{
+		blueprint: bp,
+		count:     count,
+		err:       err,
 	}
 }
 
@@ -133,13 +523,12 @@ func BuildSlice(f *Factory, count int, opts ...factoryOption) to {
 func Create(f *Factory, opts ...factoryOption) to {
 	bp := newDefaultBlueprintForCreate(f)
 
-	for _, opt := range opts {
-		opt(bp)
-	}
+	err := applyFactoryOptions(bp, opts)
 
 	return &createTo{
 		blueprint:    bp,
-		dbConnection: getDB(),
+		dbConnection: bp.dbExecutor(),
+		err:          err,
 	}
 }
 
@@ -156,25 +545,66 @@ func Create(f *Factory, opts ...factoryOption) to {
 func CreateSlice(f *Factory, count int, opts ...factoryOption) to {
 	bp := newDefaultBlueprintForCreate(f)
 
-	for _, opt := range opts {
-		opt(bp)
-	}
+	err := applyFactoryOptions(bp, opts)
 
 	return &createSliceTo{
 		blueprint:    bp,
 		count:        count,
-		dbConnection: getDB(),
+		dbConnection: bp.dbExecutor(),
+		err:          err,
 	}
 }
 
 // Delete deletes an instance of a factory model from database.
+// Options like WithTraits, WithContext and WithExecutor are used by the callbacks and the SQL statements.
 // Example:
 // err := Delete(FactoryModel, Model{})
 //
-func Delete(f *Factory, instance interface{}) error {
+func Delete(f *Factory, instance interface{}, opts ...factoryOption) error {
 	bp := newDefaultBlueprintForDelete(f)
 
-	return bp.delete(getDB(), instance)
+	if err := applyFactoryOptions(bp, opts); err != nil {
+		return err
+	}
+
+	return bp.delete(bp.dbExecutor(), instance)
+}
+
+func applyFactoryOptions(bp *blueprint, opts []factoryOption) error {
+	for _, opt := range opts {
+		if err := opt(bp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// definedAssociationFieldValue finds the association defined in the factory or its traits by field name.
+func definedAssociationFieldValue(f *Factory, name string) (*AssociationFieldValue, bool) {
+	if fieldValue, ok := f.FieldValue(name); ok {
+		associationFieldValue, ok := fieldValue.(*AssociationFieldValue)
+		return associationFieldValue, ok
+	}
+	for _, traitFactory := range f.Traits {
+		if fieldValue, ok := traitFactory.FieldValue(name); ok {
+			associationFieldValue, ok := fieldValue.(*AssociationFieldValue)
+			return associationFieldValue, ok
+		}
+	}
+	return nil, false
+}
+
+// definedTransient finds the default value of the transient field defined in the factory or its traits by name.
+func definedTransient(f *Factory, name string) (interface{}, bool) {
+	if value, ok := f.Transients[name]; ok {
+		return value, true
+	}
+	for _, traitFactory := range f.Traits {
+		if value, ok := traitFactory.Transients[name]; ok {
+			return value, true
+		}
+	}
+	return nil, false
 }
 
 // the following code are duplicated with "github.com/nauyey/factory/def"
@@ -1,8 +1,13 @@
 package factory_test
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -27,6 +32,12 @@ type testBlog struct {
 	Content  string
 	AuthorID int64
 	Author   *testUser
+	Tags     []*testTag
+}
+
+type testTag struct {
+	ID   int64
+	Name string
 }
 
 type testComment struct {
@@ -38,6 +49,14 @@ type testComment struct {
 	User   *testUser
 }
 
+type testNote struct {
+	ID              int64
+	Text            string
+	CommentableType string
+	CommentableID   int64
+	Commentable     interface{}
+}
+
 type relation struct {
 	Author *testUser
 }
@@ -182,6 +201,302 @@ func TestBuild(t *testing.T) {
 	)
 }
 
+type testStatus string
+
+type testAccount struct {
+	ID       int64
+	Age      int32
+	Status   testStatus
+	Nickname *string
+	Tags     []string
+	Score    float64
+	Extra    interface{}
+}
+
+func TestBuildWithConvertibleValue(t *testing.T) {
+	accountFactory := def.NewFactory(testAccount{}, "",
+		def.SequenceField("ID", 1, func(n int64) (interface{}, error) {
+			return int(n), nil
+		}),
+		def.Field("Status", "active"),
+		def.Field("Tags", nil),
+		def.DynamicField("Score", func(model interface{}) (interface{}, error) {
+			return 100, nil
+		}),
+	)
+
+	account := &testAccount{}
+	err := Build(accountFactory,
+		WithField("Age", 30),
+		WithField("Nickname", "nick"),
+	).To(account)
+	if err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if account.ID != 1 || account.Age != 30 || account.Status != "active" || account.Tags != nil || account.Score != 100 {
+		t.Errorf("Build with convertible values failed with account=%+v", account)
+	}
+	if account.Nickname == nil || *account.Nickname != "nick" {
+		t.Errorf("Build with convertible values failed with Nickname=%v, want pointer to \"nick\"", account.Nickname)
+	}
+
+	// Test nil for pointer field
+	account = &testAccount{}
+	if err := Build(accountFactory, WithField("Nickname", nil)).To(account); err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if account.Nickname != nil {
+		t.Errorf("Build with nil value failed with Nickname=%v, want nil", account.Nickname)
+	}
+
+	// Test auto-addressed pointers aren't shared by instances
+	nicknameFactory := def.NewFactory(testAccount{}, "",
+		def.Field("Nickname", "nick"),
+		def.Field("Extra", nil),
+	)
+	a, b := &testAccount{}, &testAccount{}
+	if err := Build(nicknameFactory).To(a); err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if err := Build(nicknameFactory).To(b); err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if a.Nickname == nil || b.Nickname == nil || a.Nickname == b.Nickname || *b.Nickname != "nick" {
+		t.Errorf("Build with auto-addressed value failed with a.Nickname=%v, b.Nickname=%v, want different pointers to \"nick\"", a.Nickname, b.Nickname)
+	}
+	*a.Nickname = "changed"
+	if *b.Nickname != "nick" {
+		t.Errorf("Build with auto-addressed value shares pointer with b.Nickname=%s", *b.Nickname)
+	}
+
+	// Test nil for interface field
+	if a.Extra != nil {
+		t.Errorf("Build with nil value failed with Extra=%v, want nil", a.Extra)
+	}
+	account = &testAccount{}
+	if err := Build(accountFactory, WithField("Extra", nil)).To(account); err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+
+	// Test values which can't be converted
+	if err := Build(accountFactory, WithField("Age", 1<<40)).To(&testAccount{}); err == nil {
+		t.Errorf("Build with overflowing value should fail")
+	}
+	if err := Build(accountFactory, WithField("Age", nil)).To(&testAccount{}); err == nil {
+		t.Errorf("Build with nil value for int32 field should fail")
+	}
+	if err := Build(accountFactory, WithField("Status", 1)).To(&testAccount{}); err == nil {
+		t.Errorf("Build with int value for string field should fail")
+	}
+
+	// Test generated values which can't be converted
+	invalidFactory := def.NewFactory(testAccount{}, "",
+		def.DynamicField("Age", func(model interface{}) (interface{}, error) {
+			return "thirty", nil
+		}),
+	)
+	if err := Build(invalidFactory).To(&testAccount{}); err == nil {
+		t.Errorf("Build with invalid generated value should fail")
+	}
+}
+
+func TestBuildWithTransient(t *testing.T) {
+	// define user factory
+	var blogsCount int
+	userFactory := def.NewFactory(testUser{}, "",
+		def.Transient("Upcased", false),
+		def.Transient("BlogsCount", 1),
+		def.DependentField("Name", func(model interface{}, e *Evaluator) (interface{}, error) {
+			if e.Transient("Upcased").(bool) {
+				return "TEST NAME", nil
+			}
+			return "test name", nil
+		}),
+		def.AfterBuildWithEvaluator(func(model interface{}, e *Evaluator) error {
+			blogsCount = e.Transient("BlogsCount").(int)
+			return nil
+		}),
+		def.Trait("upcased",
+			def.Transient("Upcased", true),
+		),
+	)
+
+	// Test Build with default transient fields
+	user := &testUser{}
+	err := Build(userFactory).To(user)
+	if err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if user.Name != "test name" {
+		t.Errorf("Build with transient failed with Name=%s, want Name=\"test name\"", user.Name)
+	}
+	if blogsCount != 1 {
+		t.Errorf("Build with transient failed with BlogsCount=%d, want BlogsCount=1", blogsCount)
+	}
+
+	// Test Build with transient fields in trait
+	user = &testUser{}
+	err = Build(userFactory, WithTraits("upcased")).To(user)
+	if err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if user.Name != "TEST NAME" {
+		t.Errorf("Build with transient failed with Name=%s, want Name=\"TEST NAME\"", user.Name)
+	}
+
+	// Test Build with overridden transient fields
+	user = &testUser{}
+	err = Build(userFactory,
+		WithTraits("upcased"),
+		WithTransient("Upcased", false),
+		WithTransient("BlogsCount", 10),
+	).To(user)
+	if err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if user.Name != "test name" {
+		t.Errorf("Build with transient failed with Name=%s, want Name=\"test name\"", user.Name)
+	}
+	if blogsCount != 10 {
+		t.Errorf("Build with transient failed with BlogsCount=%d, want BlogsCount=10", blogsCount)
+	}
+
+	// Test Build with transient value converted to the type of the default value
+	err = Build(userFactory, WithTransient("BlogsCount", int64(3))).To(&testUser{})
+	if err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if blogsCount != 3 {
+		t.Errorf("Build with converted transient failed with BlogsCount=%d, want BlogsCount=3", blogsCount)
+	}
+
+	// Test Build with undefined transient field
+	err = Build(userFactory, WithTransient("Name", "test name")).To(&testUser{})
+	if err == nil {
+		t.Errorf("Build with undefined transient field should fail")
+	}
+
+	// Test Build with transient field of invalid type
+	err = Build(userFactory, WithTransient("BlogsCount", "10")).To(&testUser{})
+	if err == nil {
+		t.Errorf("Build with transient field of invalid type should fail")
+	}
+}
+
+func TestBuildWithDependentField(t *testing.T) {
+	// define user factory
+	userFactory := def.NewFactory(testUser{}, "",
+		def.DependentField("Country", func(model interface{}, e *Evaluator) (interface{}, error) {
+			name, err := e.Field("NickName")
+			if err != nil {
+				return nil, err
+			}
+			return fmt.Sprintf("Country of %s", name), nil
+		}),
+		def.DependentField("NickName", func(model interface{}, e *Evaluator) (interface{}, error) {
+			name, err := e.Field("Name")
+			if err != nil {
+				return nil, err
+			}
+			return fmt.Sprintf("Little %s", name), nil
+		}),
+		def.DynamicField("Name", func(model interface{}) (interface{}, error) {
+			return "Ming", nil
+		}),
+		def.Trait("cyclic",
+			def.DependentField("Name", func(model interface{}, e *Evaluator) (interface{}, error) {
+				return e.Field("Country")
+			}),
+		),
+	)
+
+	// Test Build with dependent fields
+	user := &testUser{}
+	err := Build(userFactory).To(user)
+	if err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	checkUser(t, "Test Build with dependent fields",
+		&testUser{
+			Name:     "Ming",
+			NickName: "Little Ming",
+			Country:  "Country of Little Ming",
+		},
+		user,
+	)
+
+	// Test dynamic fields only see the generated fields defined before them
+	orderedFactory := def.NewFactory(testUser{}, "",
+		def.DynamicField("NickName", func(model interface{}) (interface{}, error) {
+			return fmt.Sprintf("Little %s", model.(*testUser).Name), nil
+		}),
+		def.DynamicField("Name", func(model interface{}) (interface{}, error) {
+			return "Ming", nil
+		}),
+		def.DynamicField("Country", func(model interface{}) (interface{}, error) {
+			return fmt.Sprintf("Country of %s", model.(*testUser).Name), nil
+		}),
+	)
+	user = &testUser{}
+	if err := Build(orderedFactory).To(user); err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if user.NickName != "Little " || user.Country != "Country of Ming" {
+		t.Errorf("Build with dynamic fields failed with NickName=%q, Country=%q, want NickName=\"Little \", Country=\"Country of Ming\"", user.NickName, user.Country)
+	}
+
+	// Test Build with cyclic dependent fields
+	err = Build(userFactory, WithTraits("cyclic")).To(&testUser{})
+	if err == nil {
+		t.Fatalf("Build with cyclic dependent fields should fail")
+	}
+	if !strings.Contains(err.Error(), "Country -> NickName -> Name -> Country") {
+		t.Errorf("Build with cyclic dependent fields failed with unexpected error: %v", err)
+	}
+}
+
+func TestBuildInDefinitionOrder(t *testing.T) {
+	var order []string
+	record := func(name string) DynamicFieldValue {
+		return func(model interface{}) (interface{}, error) {
+			order = append(order, name)
+			return name, nil
+		}
+	}
+
+	// define factories
+	userFactory := def.NewFactory(testUser{}, "",
+		def.DynamicField("Name", record("User")),
+	)
+	blogFactory := def.NewFactory(testBlog{}, "",
+		def.DynamicField("Title", record("Blog")),
+	)
+	commentaryFactory := def.NewFactory(testCommentary{}, "",
+		def.DynamicField("Title", record("Title")),
+		def.Association("Comment.Blog", "Comment.BlogID", "ID", blogFactory),
+		def.DynamicField("Content", record("Content")),
+		def.Association("R.Author", "AuthorID", "ID", userFactory),
+		def.DynamicField("Comment.Text", record("Comment.Text")),
+		def.Trait("with overridden content",
+			def.DynamicField("Content", record("Overridden Content")),
+		),
+	)
+
+	// Test Build in definition order
+	for i := 0; i < 10; i++ {
+		order = nil
+		err := Build(commentaryFactory, WithTraits("with overridden content")).To(&testCommentary{})
+		if err != nil {
+			t.Fatalf("Build failed with error: %v", err)
+		}
+
+		want := "Blog,User,Title,Overridden Content,Comment.Text"
+		if got := strings.Join(order, ","); got != want {
+			t.Fatalf("Build in definition order failed with order=%s, want order=%s", got, want)
+		}
+	}
+}
+
 func TestBuildWithAssociation(t *testing.T) {
 	// define user factory
 	userFactory := def.NewFactory(testUser{}, "",
@@ -287,7 +602,7 @@ func TestBuildOneToManyAssociation(t *testing.T) {
 	}
 }
 
-func TestBuildWithChainedField(t *testing.T) {
+func TestBuildWithAssociationStrategy(t *testing.T) {
 	// define user factory
 	userFactory := def.NewFactory(testUser{}, "",
 		def.Field("Name", "test name"),
@@ -295,137 +610,891 @@ func TestBuildWithChainedField(t *testing.T) {
 			return n, nil
 		}),
 	)
-	// define commentary factory
-	commentaryFactory := def.NewFactory(testCommentary{}, "",
-		def.SequenceField("ID", 1, func(n int64) (interface{}, error) {
-			return n, nil
-		}),
-		def.DynamicField("Title", func(commentaryIfac interface{}) (interface{}, error) {
-			commentary, ok := commentaryIfac.(*testCommentary)
-			if !ok {
-				return nil, fmt.Errorf("set field Title failed")
-			}
-			return fmt.Sprintf("Blog Title %d", commentary.ID), nil
-		}),
-		def.Association("R.Author", "AuthorID", "ID", userFactory,
-			def.Field("Name", "commentary author name"),
-		),
-		def.Trait("with comment text",
-			def.Field("Comment.Text", "chain comment text"),
+	// define blog factory
+	blogFactory := def.NewFactory(testBlog{}, "",
+		def.Association("Author", "AuthorID", "ID", userFactory,
+			def.Strategy(StrategyBuild),
 		),
 	)
 
-	// Test Build with chained field
-	commentary := &testCommentary{}
-	err := Build(commentaryFactory, WithTraits("with comment text")).To(commentary)
+	// Test Build with association referred by id
+	blog := &testBlog{}
+	err := Build(blogFactory, WithAssociationID("Author", int64(42))).To(blog)
 	if err != nil {
 		t.Fatalf("Build failed with error: %v", err)
 	}
-	if commentary.Comment.Text != "chain comment text" {
-		t.Fatalf("Build failed with chained field with Comment.Text=%s, want Comment.Text=\"chain comment text\"", commentary.Comment.Text)
+	if blog.Author != nil {
+		t.Errorf("Build with association id failed with Author=%v, want Author=nil", blog.Author)
+	}
+	if blog.AuthorID != 42 {
+		t.Errorf("Build with association id failed with AuthorID=%d, want AuthorID=42", blog.AuthorID)
 	}
 
-	// Test Build Associations with chained field
-	commentary = &testCommentary{}
-	err = Build(commentaryFactory).To(commentary)
-	if err != nil {
-		t.Fatalf("Build failed with error: %v", err)
+	// Test Build with association id of invalid type
+	err = Build(blogFactory, WithAssociationID("Author", "42")).To(&testBlog{})
+	if err == nil {
+		t.Errorf("Build with association id of invalid type should fail")
 	}
-	if commentary.R == nil {
-		t.Fatalf("Build failed with chained field with R=nil")
+
+	// Test Build with invalid association strategy
+	err = Build(blogFactory, WithAssociationStrategy("Author", "invalid")).To(&testBlog{})
+	if err == nil {
+		t.Errorf("Build with invalid association strategy should fail")
 	}
-	if commentary.R.Author == nil {
-		t.Fatalf("Build failed with chained field with R.Author=nil")
+
+	// Test Build with created association without database connection
+	createdAuthorFactory := def.NewFactory(testBlog{}, "",
+		def.Association("Author", "AuthorID", "ID", userFactory,
+			def.Strategy(StrategyCreate),
+		),
+	)
+	err = Build(createdAuthorFactory).To(&testBlog{})
+	if err == nil || !strings.Contains(err.Error(), "database connection isn't set") {
+		t.Errorf("Build with created association without database connection failed with err=%v", err)
 	}
-	if commentary.R.Author.Name != "commentary author name" {
-		t.Errorf("Build failed with chained field with R.Author.Name=%s, want R.Author.Name = \"commentary author name\"", commentary.R.Author.Name)
+
+	// Test Build with association strategy
+	blog = &testBlog{}
+	err = Build(blogFactory, WithAssociationStrategy("Author", StrategyBuild)).To(blog)
+	if err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if blog.Author == nil || blog.AuthorID != blog.Author.ID {
+		t.Errorf("Build with association strategy failed with Author=%v, AuthorID=%d", blog.Author, blog.AuthorID)
 	}
 }
 
-func TestBuildSlice(t *testing.T) {
+func TestBuildPolymorphicAssociation(t *testing.T) {
 	// define user factory
 	userFactory := def.NewFactory(testUser{}, "",
 		def.Field("Name", "test name"),
+		def.SequenceField("ID", 100, func(n int64) (interface{}, error) {
+			return n, nil
+		}),
+	)
+	// define blog factory
+	blogFactory := def.NewFactory(testBlog{}, "",
 		def.SequenceField("ID", 1, func(n int64) (interface{}, error) {
 			return n, nil
 		}),
 	)
+	// define note factory
+	noteFactory := def.NewFactory(testNote{}, "",
+		def.Field("Text", "note text"),
+		def.PolymorphicAssociation("Commentable", "CommentableType", "CommentableID", "ID", blogFactory,
+			def.Field("Title", "commented blog"),
+		),
+	)
 
-	// test build []*Type slice
-	users := []*testUser{}
-	err := BuildSlice(userFactory, 3, WithField("Name", "test build slice name")).To(&users)
+	// Test Build polymorphic association with default factory
+	note := &testNote{}
+	err := Build(noteFactory).To(note)
 	if err != nil {
-		t.Fatalf("BuildSlice failed with err=%v", err)
+		t.Fatalf("Build failed with error: %v", err)
 	}
-	if len(users) != 3 {
-		t.Fatalf("BuildSlice failed with len(users)=%d, want len(users)=3", len(users))
+	blog, ok := note.Commentable.(*testBlog)
+	if !ok {
+		t.Fatalf("Build polymorphic association failed with Commentable=%v, want type *testBlog", note.Commentable)
 	}
-	for i, user := range users {
-		checkUser(t, "Test BuildSlice",
-			&testUser{
-				ID:   int64(i) + 1,
-				Name: "test build slice name",
-			},
-			user,
-		)
+	if blog.Title != "commented blog" {
+		t.Errorf("Build polymorphic association failed with Title=%s, want Title=\"commented blog\"", blog.Title)
+	}
+	if note.CommentableType != "testBlog" || note.CommentableID != blog.ID {
+		t.Errorf("Build polymorphic association failed with CommentableType=%s, CommentableID=%d, want CommentableType=testBlog, CommentableID=%d",
+			note.CommentableType, note.CommentableID, blog.ID)
 	}
 
-	// test build []Type slice
-	users2 := []testUser{}
-	err = BuildSlice(userFactory, 3, WithField("Name", "test build slice name")).To(&users2)
+	// Test Build polymorphic association with chosen factory
+	note = &testNote{}
+	err = Build(noteFactory, WithAssociation("Commentable", userFactory)).To(note)
 	if err != nil {
-		t.Fatalf("BuildSlice failed with err=%v", err)
-	}
-	if len(users2) != 3 {
-		t.Fatalf("BuildSlice failed with len(users2)=%d, want len(users2)=3", len(users2))
+		t.Fatalf("Build failed with error: %v", err)
 	}
-	for i, user := range users2 {
-		checkUser(t, "Test BuildSlice",
-			&testUser{
-				ID:   int64(i) + 4,
-				Name: "test build slice name",
-			},
-			&user,
-		)
+	user, ok := note.Commentable.(*testUser)
+	if !ok {
+		t.Fatalf("Build polymorphic association failed with Commentable=%v, want type *testUser", note.Commentable)
 	}
-
-	// test build with count=0
-	users = []*testUser{}
-	err = BuildSlice(userFactory, 0, WithField("Name", "test build slice name")).To(&users)
-	if err != nil {
-		t.Fatalf("BuildSlice failed with err=%v", err)
+	if user.Name != "test name" {
+		t.Errorf("Build polymorphic association failed with Name=%s, want Name=\"test name\"", user.Name)
 	}
-	if len(users) != 0 {
-		t.Fatalf("BuildSlice failed with len(users)=%d, want len(users)=0", len(users))
+	if note.CommentableType != "testUser" || note.CommentableID != 100 {
+		t.Errorf("Build polymorphic association failed with CommentableType=%s, CommentableID=%d, want CommentableType=testUser, CommentableID=100",
+			note.CommentableType, note.CommentableID)
 	}
-}
 
-func checkUser(t *testing.T, name string, expect *testUser, got *testUser) {
-	if got.ID != expect.ID {
-		t.Errorf("Case %s: failed with ID=%d, want ID=%d", name, got.ID, expect.ID)
+	// Test Build polymorphic association with custom type values
+	typedNoteFactory := def.NewFactory(testNote{}, "",
+		def.PolymorphicAssociation("Commentable", "CommentableType", "CommentableID", "ID", blogFactory),
+		def.PolymorphicType("Commentable", blogFactory, "blogs"),
+	)
+	note = &testNote{}
+	if err := Build(typedNoteFactory).To(note); err != nil {
+		t.Fatalf("Build failed with error: %v", err)
 	}
-	if got.Name != expect.Name {
-		t.Errorf("Case %s: failed with Name=%s, want Name=%s", name, got.Name, expect.Name)
+	if note.CommentableType != "blogs" {
+		t.Errorf("Build polymorphic association with type value failed with CommentableType=%s, want CommentableType=blogs", note.CommentableType)
 	}
-	if got.NickName != expect.NickName {
-		t.Errorf("Case %s: failed with NickName=%s, want NickName=%s", name, got.NickName, expect.NickName)
+	note = &testNote{}
+	if err := Build(typedNoteFactory, WithAssociation("Commentable", userFactory)).To(note); err != nil {
+		t.Fatalf("Build failed with error: %v", err)
 	}
-	if got.Age != expect.Age {
-		t.Errorf("Case %s: failed with Age=%d, want Age=%d", name, got.Age, expect.Age)
+	if note.CommentableType != "testUser" {
+		t.Errorf("Build polymorphic association with default type value failed with CommentableType=%s, want CommentableType=testUser", note.CommentableType)
 	}
-	if got.Country != expect.Country {
-		t.Errorf("Case %s: failed with Country=%s, want Country=%s", name, got.Country, expect.Country)
+	note = &testNote{}
+	if err := Build(typedNoteFactory, WithAssociationInstance("Commentable", &testBlog{ID: 7})).To(note); err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if note.CommentableType != "blogs" || note.CommentableID != 7 {
+		t.Errorf("Build polymorphic association with instance failed with CommentableType=%s, CommentableID=%d, want CommentableType=blogs, CommentableID=7",
+			note.CommentableType, note.CommentableID)
 	}
-}
 
-func checkBlog(t *testing.T, name string, expect *testBlog, got *testBlog) {
-	if got.ID != expect.ID {
-		t.Errorf("Case %s: failed with ID=%d, want ID=%d", name, got.ID, expect.ID)
+	// Test Build with chosen factory without the association reference field
+	type testCode struct {
+		Code string
 	}
-	if got.Title != expect.Title {
-		t.Errorf("Case %s: failed with Title=%s, want Title=%s", name, got.Title, expect.Title)
+	codeFactory := def.NewFactory(testCode{}, "",
+		def.Field("Code", "code"),
+	)
+	err = Build(noteFactory, WithAssociation("Commentable", codeFactory)).To(&testNote{})
+	if err == nil || !strings.Contains(err.Error(), "invalid field name ID") {
+		t.Errorf("Build with factory without reference field failed with err=%v", err)
 	}
-	if got.Content != expect.Content {
+
+	// Test Build with chosen factory whose reference field can't be converted
+	type testSlug struct {
+		ID string
+	}
+	slugFactory := def.NewFactory(testSlug{}, "",
+		def.Field("ID", "slug"),
+	)
+	err = Build(noteFactory, WithAssociation("Commentable", slugFactory)).To(&testNote{})
+	if err == nil || !strings.Contains(err.Error(), "of field CommentableID") {
+		t.Errorf("Build with factory of unconvertible reference field failed with err=%v", err)
+	}
+
+	// Test Build with chosen factory whose model can't be the association
+	type testBlogNote struct {
+		Text            string
+		CommentableType string
+		CommentableID   int64
+		Commentable     *testBlog
+	}
+	blogNoteFactory := def.NewFactory(testBlogNote{}, "",
+		def.PolymorphicAssociation("Commentable", "CommentableType", "CommentableID", "ID", blogFactory),
+	)
+	err = Build(blogNoteFactory, WithAssociation("Commentable", userFactory)).To(&testBlogNote{})
+	if err == nil || !strings.Contains(err.Error(), "cannot use factory of type testUser as association Commentable") {
+		t.Errorf("Build with factory of unassignable model failed with err=%v", err)
+	}
+
+	// Test Build with undefined association
+	err = Build(noteFactory, WithAssociation("Text", userFactory)).To(&testNote{})
+	if err == nil {
+		t.Errorf("Build with undefined association should fail")
+	}
+}
+
+func TestBuildWithAssociationOverrides(t *testing.T) {
+	// define user factory
+	userFactory := def.NewFactory(testUser{}, "",
+		def.Field("Name", "test name"),
+		def.SequenceField("ID", 1, func(n int64) (interface{}, error) {
+			return n, nil
+		}),
+		def.Trait("Chinese",
+			def.Field("Country", "China"),
+		),
+	)
+	// define blog factory
+	blogFactory := def.NewFactory(testBlog{}, "",
+		def.Association("Author", "AuthorID", "ID", userFactory,
+			def.Field("NickName", "author nick name"),
+		),
+	)
+	// define note factory
+	noteFactory := def.NewFactory(testNote{}, "",
+		def.PolymorphicAssociation("Commentable", "CommentableType", "CommentableID", "ID", blogFactory),
+	)
+
+	// Test Build with association options
+	blog := &testBlog{}
+	err := Build(blogFactory, WithAssociation("Author", WithTraits("Chinese"), WithField("Name", "author name"))).To(blog)
+	if err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if blog.Author == nil {
+		t.Fatalf("Build with association options failed with Author=nil")
+	}
+	if blog.Author.Name != "author name" || blog.Author.Country != "China" || blog.Author.NickName != "author nick name" {
+		t.Errorf("Build with association options failed with Name=%s, Country=%s, NickName=%s",
+			blog.Author.Name, blog.Author.Country, blog.Author.NickName)
+	}
+	if blog.AuthorID != blog.Author.ID {
+		t.Errorf("Build with association options failed with AuthorID=%d, want AuthorID=%d", blog.AuthorID, blog.Author.ID)
+	}
+
+	// Test Build with invalid association options
+	err = Build(blogFactory, WithAssociation("Author", WithTraits("undefined"))).To(&testBlog{})
+	if err == nil {
+		t.Errorf("Build with undefined association trait should fail")
+	}
+	err = Build(blogFactory, WithAssociation("Author", WithField("Title", "title"))).To(&testBlog{})
+	if err == nil {
+		t.Errorf("Build with undefined association field should fail")
+	}
+
+	// Test Build polymorphic association with chosen factory and options
+	note := &testNote{}
+	err = Build(noteFactory, WithAssociation("Commentable", userFactory, WithField("Name", "commenter"))).To(note)
+	if err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	user, ok := note.Commentable.(*testUser)
+	if !ok || user.Name != "commenter" || note.CommentableType != "testUser" {
+		t.Errorf("Build polymorphic association with options failed with Commentable=%v, CommentableType=%s", note.Commentable, note.CommentableType)
+	}
+
+	// Test Build with association instance
+	author := &testUser{ID: 42, Name: "existing author"}
+	blog = &testBlog{}
+	err = Build(blogFactory, WithAssociationInstance("Author", author)).To(blog)
+	if err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if blog.Author != author || blog.AuthorID != 42 {
+		t.Errorf("Build with association instance failed with Author=%v, AuthorID=%d", blog.Author, blog.AuthorID)
+	}
+
+	// Test Build polymorphic association with association instance
+	note = &testNote{}
+	err = Build(noteFactory, WithAssociationInstance("Commentable", author)).To(note)
+	if err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if note.Commentable != author || note.CommentableType != "testUser" || note.CommentableID != 42 {
+		t.Errorf("Build polymorphic association with association instance failed with Commentable=%v, CommentableType=%s, CommentableID=%d",
+			note.Commentable, note.CommentableType, note.CommentableID)
+	}
+
+	// Test Build polymorphic association without default factory by association id
+	anyNoteFactory := def.NewFactory(testNote{}, "",
+		def.PolymorphicAssociation("Commentable", "CommentableType", "CommentableID", "ID", nil),
+	)
+	err = Build(anyNoteFactory, WithAssociationID("Commentable", int64(42))).To(&testNote{})
+	if err == nil || !strings.Contains(err.Error(), "no factory is chosen for association Commentable") {
+		t.Errorf("Build polymorphic association by id without factory failed with err=%v", err)
+	}
+	note = &testNote{}
+	err = Build(anyNoteFactory, WithAssociation("Commentable", userFactory), WithAssociationID("Commentable", int64(42))).To(note)
+	if err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if note.Commentable != nil || note.CommentableType != "testUser" || note.CommentableID != 42 {
+		t.Errorf("Build polymorphic association by id with chosen factory failed with Commentable=%v, CommentableType=%s, CommentableID=%d",
+			note.Commentable, note.CommentableType, note.CommentableID)
+	}
+
+	// Test Build with association instance of invalid type
+	err = Build(blogFactory, WithAssociationInstance("Author", &testBlog{})).To(&testBlog{})
+	if err == nil {
+		t.Errorf("Build with association instance of invalid type should fail")
+	}
+	err = Build(blogFactory, WithAssociationInstance("Author", nil)).To(&testBlog{})
+	if err == nil {
+		t.Errorf("Build with nil association instance should fail")
+	}
+}
+
+func TestBuildWithoutFieldAndAssociation(t *testing.T) {
+	// define user factory
+	userFactory := def.NewFactory(testUser{}, "",
+		def.Field("Name", "test name"),
+		def.SequenceField("ID", 1, func(n int64) (interface{}, error) {
+			return n, nil
+		}),
+		def.DynamicField("NickName", func(user interface{}) (interface{}, error) {
+			return "nick name", nil
+		}),
+	)
+	// define blog factory
+	blogFactory := def.NewFactory(testBlog{}, "",
+		def.Field("Title", "blog title"),
+		def.Association("Author", "AuthorID", "ID", userFactory),
+	)
+
+	// Test Build without fields
+	user := &testUser{}
+	err := Build(userFactory, WithoutField("Name"), WithoutField("NickName"), WithoutField("ID")).To(user)
+	if err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if user.Name != "" || user.NickName != "" || user.ID != 0 {
+		t.Errorf("Build without fields failed with Name=%s, NickName=%s, ID=%d", user.Name, user.NickName, user.ID)
+	}
+	// the sequence isn't consumed
+	user = &testUser{}
+	err = Build(userFactory).To(user)
+	if err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if user.ID != 1 {
+		t.Errorf("Build without field consumed the sequence with ID=%d, want ID=1", user.ID)
+	}
+
+	// Test Build with field set after skipped
+	user = &testUser{}
+	err = Build(userFactory, WithoutField("Name"), WithField("Name", "new name")).To(user)
+	if err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if user.Name != "new name" {
+		t.Errorf("Build with field set after skipped failed with Name=%s, want Name=\"new name\"", user.Name)
+	}
+
+	err = Build(userFactory, WithoutField("Unknown")).To(&testUser{})
+	if err == nil {
+		t.Errorf("Build without unknown field should fail")
+	}
+
+	// Test Build without association
+	blog := &testBlog{}
+	err = Build(blogFactory, WithoutAssociation("Author")).To(blog)
+	if err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if blog.Author != nil || blog.AuthorID != 0 || blog.Title != "blog title" {
+		t.Errorf("Build without association failed with Author=%v, AuthorID=%d, Title=%s", blog.Author, blog.AuthorID, blog.Title)
+	}
+
+	// Test Build with association without field
+	blog = &testBlog{}
+	err = Build(blogFactory, WithAssociation("Author", WithoutField("Name"))).To(blog)
+	if err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if blog.Author == nil || blog.Author.Name != "" {
+		t.Errorf("Build with association without field failed with Author=%v", blog.Author)
+	}
+
+	err = Build(blogFactory, WithoutAssociation("Title")).To(&testBlog{})
+	if err == nil {
+		t.Errorf("Build without undefined association should fail")
+	}
+}
+
+func TestBuildManyToManyAssociation(t *testing.T) {
+	// define tag factory
+	tagFactory := def.NewFactory(testTag{}, "",
+		def.SequenceField("ID", 1, func(n int64) (interface{}, error) {
+			return n, nil
+		}),
+		def.Field("Name", "tag name"),
+	)
+	// define blog factory
+	blogFactory := def.NewFactory(testBlog{}, "",
+		def.SequenceField("ID", 1, func(n int64) (interface{}, error) {
+			return n, nil
+		}),
+		def.ManyToMany("Tags", tagFactory, "blog_tags", "blog_id", "tag_id", 3,
+			def.Field("Name", "blog tag name"),
+		),
+	)
+
+	// Test Build many-to-many association
+	blog := &testBlog{}
+	err := Build(blogFactory).To(blog)
+	if err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if len(blog.Tags) != 3 {
+		t.Fatalf("Build many-to-many association failed with len(Tags)=%d, want len(Tags)=3", len(blog.Tags))
+	}
+	for i, tag := range blog.Tags {
+		if tag.ID != int64(i)+1 {
+			t.Errorf("Build many-to-many association failed with ID=%d, want ID=%d", tag.ID, i+1)
+		}
+		if tag.Name != "blog tag name" {
+			t.Errorf("Build many-to-many association failed with Name=%s, want Name=\"blog tag name\"", tag.Name)
+		}
+	}
+}
+
+func TestCreateManyToManyAssociation(t *testing.T) {
+	type testLabel struct {
+		ID   int64  `factory:"id,primary"`
+		Name string `factory:"name"`
+	}
+	type testPost struct {
+		ID     int64  `factory:"id,primary"`
+		Title  string `factory:"title"`
+		Labels []*testLabel
+	}
+
+	db, database := openTestDB()
+	defer db.Close()
+
+	// define label factory
+	labelFactory := def.NewFactory(testLabel{}, "labels",
+		def.SequenceField("ID", 1, func(n int64) (interface{}, error) {
+			return n, nil
+		}),
+		def.Field("Name", "label name"),
+	)
+	// define post factory
+	postFactory := def.NewFactory(testPost{}, "posts",
+		def.SequenceField("ID", 1, func(n int64) (interface{}, error) {
+			return n, nil
+		}),
+		def.Field("Title", "post title"),
+		def.ManyToMany("Labels", labelFactory, "post_labels", "post_id", "label_id", 2),
+	)
+
+	// Test Create many-to-many association
+	post := &testPost{}
+	err := Create(postFactory, WithExecutor(db)).To(post)
+	if err != nil {
+		t.Fatalf("Create failed with error: %v", err)
+	}
+	if len(post.Labels) != 2 {
+		t.Fatalf("Create many-to-many association failed with len(Labels)=%d, want len(Labels)=2", len(post.Labels))
+	}
+	if rows := database.rows("labels"); len(rows) != 2 {
+		t.Errorf("Create many-to-many association failed with %d label rows, want 2 rows", len(rows))
+	}
+	joinRows := database.rows("post_labels")
+	if len(joinRows) != 2 {
+		t.Fatalf("Create many-to-many association failed with %d join rows, want 2 rows", len(joinRows))
+	}
+	for i, row := range joinRows {
+		if row["post_id"] != post.ID || row["label_id"] != post.Labels[i].ID {
+			t.Errorf("Create many-to-many association failed with join row %v, want post_id=%d, label_id=%d", row, post.ID, post.Labels[i].ID)
+		}
+	}
+
+	// Test Delete many-to-many association
+	other := &testPost{}
+	if err := Create(postFactory, WithExecutor(db)).To(other); err != nil {
+		t.Fatalf("Create failed with error: %v", err)
+	}
+	if err := Delete(postFactory, post, WithExecutor(db)); err != nil {
+		t.Fatalf("Delete failed with error: %v", err)
+	}
+	if rows := database.rows("posts"); len(rows) != 1 || rows[0]["id"] != other.ID {
+		t.Errorf("Delete failed with post rows %v, want the row of id=%d", rows, other.ID)
+	}
+	joinRows = database.rows("post_labels")
+	if len(joinRows) != 2 {
+		t.Fatalf("Delete many-to-many association failed with %d join rows, want 2 rows", len(joinRows))
+	}
+	for _, row := range joinRows {
+		if row["post_id"] != other.ID {
+			t.Errorf("Delete many-to-many association failed with join row %v, want post_id=%d", row, other.ID)
+		}
+	}
+	if rows := database.rows("labels"); len(rows) != 4 {
+		t.Errorf("Delete many-to-many association failed with %d label rows, want 4 rows kept", len(rows))
+	}
+}
+
+func TestBuildWithSequenceRef(t *testing.T) {
+	r := NewRegistry()
+	idSequence := def.NewSequenceIn(r, "strategies test id", 1, func(n int64) (interface{}, error) {
+		return n, nil
+	})
+
+	// define factories sharing sequence
+	userFactory := def.NewFactory(testUser{}, "",
+		def.SequenceRefIn(r, "ID", idSequence),
+	)
+	blogFactory := def.NewFactory(testBlog{}, "",
+		def.SequenceRefIn(r, "ID", idSequence),
+	)
+
+	// Test Build with shared sequence
+	user := &testUser{}
+	if err := Build(userFactory).To(user); err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	blog := &testBlog{}
+	if err := Build(blogFactory).To(blog); err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if user.ID != 1 || blog.ID != 2 {
+		t.Errorf("Build with shared sequence failed with user.ID=%d, blog.ID=%d, want user.ID=1, blog.ID=2", user.ID, blog.ID)
+	}
+
+	// Test Build with shared sequence concurrently
+	const count = 50
+	ids := make(chan int64, 2*count)
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			user := &testUser{}
+			if err := Build(userFactory).To(user); err != nil {
+				t.Errorf("Build failed with error: %v", err)
+			}
+			ids <- user.ID
+		}()
+		go func() {
+			defer wg.Done()
+			blog := &testBlog{}
+			if err := Build(blogFactory).To(blog); err != nil {
+				t.Errorf("Build failed with error: %v", err)
+			}
+			ids <- blog.ID
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := map[int64]bool{}
+	for id := range ids {
+		if seen[id] {
+			t.Errorf("Build with shared sequence concurrently failed with duplicate ID=%d", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestResetSequences(t *testing.T) {
+	// define factories
+	userFactory := def.NewFactory(testUser{}, "",
+		def.SequenceField("ID", 1, func(n int64) (interface{}, error) {
+			return n, nil
+		}),
+	)
+	blogFactory := def.NewFactory(testBlog{}, "",
+		def.SequenceField("ID", 10, func(n int64) (interface{}, error) {
+			return n, nil
+		}),
+		def.Association("Author", "AuthorID", "ID", userFactory),
+	)
+
+	checkPeek := func(f *Factory, name string, want int64) {
+		n, err := PeekSequence(f, name)
+		if err != nil {
+			t.Fatalf("PeekSequence failed with error: %v", err)
+		}
+		if n != want {
+			t.Errorf("PeekSequence failed with n=%d, want n=%d", n, want)
+		}
+	}
+
+	blog := &testBlog{}
+	if err := Build(blogFactory).To(blog); err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	checkPeek(blogFactory, "ID", 11)
+
+	// Test ResetSequences rewinds sequences of associations
+	ResetSequences(blogFactory)
+	checkPeek(blogFactory, "ID", 10)
+	blog = &testBlog{}
+	if err := Build(blogFactory).To(blog); err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if blog.ID != 10 || blog.Author.ID != 1 {
+		t.Errorf("ResetSequences failed with blog.ID=%d, blog.Author.ID=%d, want blog.ID=10, blog.Author.ID=1", blog.ID, blog.Author.ID)
+	}
+
+	// Test Registry.ResetSequences rewinds the sequences of the registered factories and the named sequences
+	r := NewRegistry()
+	if err := r.Register("user", userFactory); err != nil {
+		t.Fatalf("Register failed with error: %v", err)
+	}
+	idSequence := def.NewSequenceIn(r, "reset test id", 1, func(n int64) (interface{}, error) {
+		return n, nil
+	})
+	tagFactory := def.NewFactory(testTag{}, "",
+		def.SequenceRefIn(r, "ID", idSequence),
+	)
+	user := &testUser{}
+	if err := Build(userFactory).To(user); err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if err := Build(tagFactory).To(&testTag{}); err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	r.ResetSequences()
+	checkPeek(userFactory, "ID", 1)
+	checkPeek(tagFactory, "ID", 1)
+	// the factories which aren't registered are kept
+	checkPeek(blogFactory, "ID", 11)
+
+	// Test ResetAllSequences rewinds the sequences of the factories which aren't registered
+	ResetAllSequences()
+	checkPeek(blogFactory, "ID", 10)
+	checkPeek(userFactory, "ID", 1)
+
+	// Test PeekSequence with non-sequence field
+	if _, err := PeekSequence(blogFactory, "Title"); err == nil {
+		t.Error("PeekSequence with non-sequence field should return error")
+	}
+}
+
+func TestBuildWithSequenceStart(t *testing.T) {
+	// define user factory
+	userFactory := def.NewFactory(testUser{}, "",
+		def.SequenceField("ID", 1, func(n int64) (interface{}, error) {
+			return n, nil
+		}),
+		def.Field("Name", "test name"),
+	)
+
+	// Test BuildSlice with WithSequenceStart
+	users := []*testUser{}
+	if err := BuildSlice(userFactory, 3, WithSequenceStart("ID", 1000)).To(&users); err != nil {
+		t.Fatalf("BuildSlice failed with error: %v", err)
+	}
+	for i, user := range users {
+		if user.ID != int64(1000+i) {
+			t.Errorf("BuildSlice with WithSequenceStart failed with ID=%d, want ID=%d", user.ID, 1000+i)
+		}
+	}
+
+	// Test the sequence of the factory isn't moved by WithSequenceStart
+	user := &testUser{}
+	if err := Build(userFactory).To(user); err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if user.ID != 1 {
+		t.Errorf("Build after WithSequenceStart failed with ID=%d, want ID=1", user.ID)
+	}
+	if err := Build(userFactory, WithSequenceStart("ID", 500)).To(user); err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if user.ID != 500 {
+		t.Errorf("Build with WithSequenceStart failed with ID=%d, want ID=500", user.ID)
+	}
+	if err := Build(userFactory).To(user); err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if user.ID != 2 {
+		t.Errorf("Build after WithSequenceStart failed with ID=%d, want ID=2", user.ID)
+	}
+
+	// Test reusing the result of Build with WithSequenceStart starts from n again
+	to := Build(userFactory, WithSequenceStart("ID", 10))
+	for i := 0; i < 2; i++ {
+		if err := to.To(user); err != nil {
+			t.Fatalf("Build failed with error: %v", err)
+		}
+		if user.ID != 10 {
+			t.Errorf("reused Build with WithSequenceStart failed with ID=%d, want ID=10", user.ID)
+		}
+	}
+
+	// Test WithSequenceStart with non-sequence field
+	if err := Build(userFactory, WithSequenceStart("Name", 1)).To(&testUser{}); err == nil {
+		t.Error("Build with WithSequenceStart of non-sequence field should return error")
+	}
+	if err := Build(userFactory, WithSequenceStart("NotExist", 1)).To(&testUser{}); err == nil {
+		t.Error("Build with WithSequenceStart of undefined field should return error")
+	}
+}
+
+func TestBuildWithChainedField(t *testing.T) {
+	// define user factory
+	userFactory := def.NewFactory(testUser{}, "",
+		def.Field("Name", "test name"),
+		def.SequenceField("ID", 1, func(n int64) (interface{}, error) {
+			return n, nil
+		}),
+	)
+	// define commentary factory
+	commentaryFactory := def.NewFactory(testCommentary{}, "",
+		def.SequenceField("ID", 1, func(n int64) (interface{}, error) {
+			return n, nil
+		}),
+		def.DynamicField("Title", func(commentaryIfac interface{}) (interface{}, error) {
+			commentary, ok := commentaryIfac.(*testCommentary)
+			if !ok {
+				return nil, fmt.Errorf("set field Title failed")
+			}
+			return fmt.Sprintf("Blog Title %d", commentary.ID), nil
+		}),
+		def.Association("R.Author", "AuthorID", "ID", userFactory,
+			def.Field("Name", "commentary author name"),
+		),
+		def.Trait("with comment text",
+			def.Field("Comment.Text", "chain comment text"),
+		),
+	)
+
+	// Test Build with chained field
+	commentary := &testCommentary{}
+	err := Build(commentaryFactory, WithTraits("with comment text")).To(commentary)
+	if err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if commentary.Comment.Text != "chain comment text" {
+		t.Fatalf("Build failed with chained field with Comment.Text=%s, want Comment.Text=\"chain comment text\"", commentary.Comment.Text)
+	}
+
+	// Test Build Associations with chained field
+	commentary = &testCommentary{}
+	err = Build(commentaryFactory).To(commentary)
+	if err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if commentary.R == nil {
+		t.Fatalf("Build failed with chained field with R=nil")
+	}
+	if commentary.R.Author == nil {
+		t.Fatalf("Build failed with chained field with R.Author=nil")
+	}
+	if commentary.R.Author.Name != "commentary author name" {
+		t.Errorf("Build failed with chained field with R.Author.Name=%s, want R.Author.Name = \"commentary author name\"", commentary.R.Author.Name)
+	}
+}
+
+func TestBuildWithFieldsAndStruct(t *testing.T) {
+	userFactory := def.NewFactory(testUser{}, "",
+		def.Field("Name", "test name"),
+		def.Field("NickName", "test nick name"),
+		def.Field("Age", 16),
+	)
+
+	// Test WithFields
+	user := &testUser{}
+	err := Build(userFactory, WithFields(map[string]interface{}{
+		"Name": "map name",
+		"Age":  20,
+	})).To(user)
+	if err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if user.Name != "map name" || user.Age != 20 || user.NickName != "test nick name" {
+		t.Errorf("WithFields failed with Name=%s, Age=%d, NickName=%s", user.Name, user.Age, user.NickName)
+	}
+
+	err = Build(userFactory, WithFields(map[string]interface{}{"Unknown": 1})).To(&testUser{})
+	if err == nil {
+		t.Errorf("WithFields with unknown field should fail")
+	}
+	err = Build(userFactory, WithFields(map[string]interface{}{"Age": "old"})).To(&testUser{})
+	if err == nil {
+		t.Errorf("WithFields with invalid value type should fail")
+	}
+
+	// Test WithStruct with non-zero fields
+	user = &testUser{}
+	err = Build(userFactory, WithStruct(testUser{Name: "struct name"})).To(user)
+	if err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if user.Name != "struct name" || user.Age != 16 || user.NickName != "test nick name" {
+		t.Errorf("WithStruct failed with Name=%s, Age=%d, NickName=%s", user.Name, user.Age, user.NickName)
+	}
+
+	// Test WithStruct with pointer and field mask
+	user = &testUser{}
+	err = Build(userFactory, WithStruct(&testUser{Name: "struct name", NickName: "ignored"}, "Name", "Age")).To(user)
+	if err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if user.Name != "struct name" || user.Age != 0 || user.NickName != "test nick name" {
+		t.Errorf("WithStruct with mask failed with Name=%s, Age=%d, NickName=%s", user.Name, user.Age, user.NickName)
+	}
+
+	err = Build(userFactory, WithStruct(testBlog{Title: "title"})).To(&testUser{})
+	if err == nil {
+		t.Errorf("WithStruct with another model type should fail")
+	}
+	err = Build(userFactory, WithStruct(testUser{}, "Unknown")).To(&testUser{})
+	if err == nil {
+		t.Errorf("WithStruct with unknown field should fail")
+	}
+}
+
+func TestBuildSlice(t *testing.T) {
+	// define user factory
+	userFactory := def.NewFactory(testUser{}, "",
+		def.Field("Name", "test name"),
+		def.SequenceField("ID", 1, func(n int64) (interface{}, error) {
+			return n, nil
+		}),
+	)
+
+	// test build []*Type slice
+	users := []*testUser{}
+	err := BuildSlice(userFactory, 3, WithField("Name", "test build slice name")).To(&users)
+	if err != nil {
+		t.Fatalf("BuildSlice failed with err=%v", err)
+	}
+	if len(users) != 3 {
+		t.Fatalf("BuildSlice failed with len(users)=%d, want len(users)=3", len(users))
+	}
+	for i, user := range users {
+		checkUser(t, "Test BuildSlice",
+			&testUser{
+				ID:   int64(i) + 1,
+				Name: "test build slice name",
+			},
+			user,
+		)
+	}
+
+	// test build []Type slice
+	users2 := []testUser{}
+	err = BuildSlice(userFactory, 3, WithField("Name", "test build slice name")).To(&users2)
+	if err != nil {
+		t.Fatalf("BuildSlice failed with err=%v", err)
+	}
+	if len(users2) != 3 {
+		t.Fatalf("BuildSlice failed with len(users2)=%d, want len(users2)=3", len(users2))
+	}
+	for i, user := range users2 {
+		checkUser(t, "Test BuildSlice",
+			&testUser{
+				ID:   int64(i) + 4,
+				Name: "test build slice name",
+			},
+			&user,
+		)
+	}
+
+	// test build with count=0
+	users = []*testUser{}
+	err = BuildSlice(userFactory, 0, WithField("Name", "test build slice name")).To(&users)
+	if err != nil {
+		t.Fatalf("BuildSlice failed with err=%v", err)
+	}
+	if len(users) != 0 {
+		t.Fatalf("BuildSlice failed with len(users)=%d, want len(users)=0", len(users))
+	}
+}
+
+func checkUser(t *testing.T, name string, expect *testUser, got *testUser) {
+	if got.ID != expect.ID {
+		t.Errorf("Case %s: failed with ID=%d, want ID=%d", name, got.ID, expect.ID)
+	}
+	if got.Name != expect.Name {
+		t.Errorf("Case %s: failed with Name=%s, want Name=%s", name, got.Name, expect.Name)
+	}
+	if got.NickName != expect.NickName {
+		t.Errorf("Case %s: failed with NickName=%s, want NickName=%s", name, got.NickName, expect.NickName)
+	}
+	if got.Age != expect.Age {
+		t.Errorf("Case %s: failed with Age=%d, want Age=%d", name, got.Age, expect.Age)
+	}
+	if got.Country != expect.Country {
+		t.Errorf("Case %s: failed with Country=%s, want Country=%s", name, got.Country, expect.Country)
+	}
+}
+
+func checkBlog(t *testing.T, name string, expect *testBlog, got *testBlog) {
+	if got.ID != expect.ID {
+		t.Errorf("Case %s: failed with ID=%d, want ID=%d", name, got.ID, expect.ID)
+	}
+	if got.Title != expect.Title {
+		t.Errorf("Case %s: failed with Title=%s, want Title=%s", name, got.Title, expect.Title)
+	}
+	if got.Content != expect.Content {
 		t.Errorf("Case %s: failed with Content=%s, want Content=%s", name, got.Content, expect.Content)
 	}
 	if got.AuthorID != expect.AuthorID {
@@ -433,3 +1502,248 @@ func checkBlog(t *testing.T, name string, expect *testBlog, got *testBlog) {
 	}
 	checkUser(t, name, expect.Author, got.Author)
 }
+
+func TestBuildSliceWithIndexedOptions(t *testing.T) {
+	userFactory := def.NewFactory(testUser{}, "",
+		def.Field("Name", "test name"),
+		def.SequenceField("ID", 1, func(n int64) (interface{}, error) {
+			return n, nil
+		}),
+		def.Trait("Chinese",
+			def.Field("Country", "China"),
+		),
+	)
+
+	var eachIndexes []int
+	users := []*testUser{}
+	err := BuildSlice(userFactory, 4,
+		WithSequenceStart("ID", 10),
+		WithIndexedField("Age", func(i int) interface{} {
+			return i + 1
+		}),
+		WithIndexedTraits(func(i int) []string {
+			if i%2 == 0 {
+				return []string{"Chinese"}
+			}
+			return nil
+		}),
+		WithEach(func(i int, model interface{}) error {
+			eachIndexes = append(eachIndexes, i)
+			model.(*testUser).NickName = fmt.Sprintf("user %d", i)
+			return nil
+		}),
+	).To(&users)
+	if err != nil {
+		t.Fatalf("BuildSlice failed with error: %v", err)
+	}
+	if len(users) != 4 {
+		t.Fatalf("BuildSlice failed with len(users)=%d, want 4", len(users))
+	}
+	for i, user := range users {
+		wantCountry := ""
+		if i%2 == 0 {
+			wantCountry = "China"
+		}
+		if user.ID != int64(10+i) || user.Age != int32(i+1) || user.Country != wantCountry || user.NickName != fmt.Sprintf("user %d", i) {
+			t.Errorf("BuildSlice with indexed options failed with users[%d]=%+v", i, user)
+		}
+	}
+	if fmt.Sprint(eachIndexes) != "[0 1 2 3]" {
+		t.Errorf("WithEach called with indexes %v, want [0 1 2 3]", eachIndexes)
+	}
+
+	// Test Build uses index 0
+	user := &testUser{}
+	err = Build(userFactory, WithIndexedField("Age", func(i int) interface{} {
+		return i + 20
+	})).To(user)
+	if err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if user.Age != 20 {
+		t.Errorf("Build with indexed field failed with Age=%d, want Age=20", user.Age)
+	}
+
+	// Test BuildSlice with invalid indexed options
+	err = BuildSlice(userFactory, 2, WithIndexedField("Age", func(i int) interface{} {
+		return "old"
+	})).To(&[]*testUser{})
+	if err == nil {
+		t.Errorf("BuildSlice with indexed field of invalid type should fail")
+	}
+	err = BuildSlice(userFactory, 2, WithIndexedTraits(func(i int) []string {
+		return []string{"undefined"}
+	})).To(&[]*testUser{})
+	if err == nil {
+		t.Errorf("BuildSlice with undefined indexed trait should fail")
+	}
+	err = BuildSlice(userFactory, 2, WithEach(func(i int, model interface{}) error {
+		return errors.New("each error")
+	})).To(&[]*testUser{})
+	if err == nil || err.Error() != "each error" {
+		t.Errorf("BuildSlice with WithEach error failed with err=%v, want \"each error\"", err)
+	}
+}
+
+type testExecutor struct {
+	ctx     context.Context
+	queries []string
+}
+
+func (executor *testExecutor) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	executor.ctx = ctx
+	executor.queries = append(executor.queries, query)
+	return driver.RowsAffected(1), nil
+}
+
+func (executor *testExecutor) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+type testContextKey struct{}
+
+func TestCallbackPhases(t *testing.T) {
+	type testAccount struct {
+		ID   int64  `factory:"id,primary"`
+		Name string `factory:"name"`
+	}
+
+	var phases []string
+	record := func(phase string) def.Option {
+		callback := func(model interface{}, e *Evaluator) error {
+			account := model.(*testAccount)
+			phases = append(phases, fmt.Sprintf("%s:%s:%s:%v:%v", phase, account.Name, e.Strategy(), e.Traits(), e.Context().Value(testContextKey{})))
+			return nil
+		}
+		switch phase {
+		case "BeforeBuild":
+			return def.BeforeBuildWithEvaluator(callback)
+		case "AfterBuild":
+			return def.AfterBuildWithEvaluator(callback)
+		case "AfterStub":
+			return def.AfterStubWithEvaluator(callback)
+		case "BeforeDelete":
+			return def.BeforeDeleteWithEvaluator(callback)
+		default:
+			return def.AfterDeleteWithEvaluator(callback)
+		}
+	}
+	accountFactory := def.NewFactory(testAccount{}, "account",
+		def.Field("Name", "account name"),
+		record("BeforeBuild"),
+		record("AfterBuild"),
+		record("AfterStub"),
+		record("BeforeDelete"),
+		record("AfterDelete"),
+		def.Trait("admin"),
+	)
+	ctx := context.WithValue(context.Background(), testContextKey{}, "value")
+
+	// Test Build callback phases
+	err := Build(accountFactory, WithTraits("admin"), WithContext(ctx)).To(&testAccount{})
+	if err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	want := []string{
+		"BeforeBuild::build:[admin]:value",
+		"AfterBuild:account name:build:[admin]:value",
+	}
+	if strings.Join(phases, ",") != strings.Join(want, ",") {
+		t.Errorf("Build callback phases=%v, want %v", phases, want)
+	}
+
+	// Test BuildStubbed callback phases
+	phases = nil
+	err = BuildStubbed(accountFactory).To(&testAccount{})
+	if err != nil {
+		t.Fatalf("BuildStubbed failed with error: %v", err)
+	}
+	want = []string{"AfterStub:account name:stub:[]:<nil>"}
+	if strings.Join(phases, ",") != strings.Join(want, ",") {
+		t.Errorf("BuildStubbed callback phases=%v, want %v", phases, want)
+	}
+
+	// Test Delete callback phases with executor and context
+	phases = nil
+	executor := &testExecutor{}
+	err = Delete(accountFactory, &testAccount{ID: 1, Name: "deleted"}, WithExecutor(executor), WithContext(ctx))
+	if err != nil {
+		t.Fatalf("Delete failed with error: %v", err)
+	}
+	want = []string{
+		"BeforeDelete:deleted:delete:[]:value",
+		"AfterDelete:deleted:delete:[]:value",
+	}
+	if strings.Join(phases, ",") != strings.Join(want, ",") {
+		t.Errorf("Delete callback phases=%v, want %v", phases, want)
+	}
+	if len(executor.queries) != 1 || executor.ctx != ctx {
+		t.Errorf("Delete failed with queries=%v, want 1 query executed with context", executor.queries)
+	}
+}
+
+func TestEvaluatorExecutor(t *testing.T) {
+	executor := &testExecutor{}
+	var got Executor
+	userFactory := def.NewFactory(testUser{}, "",
+		def.AfterBuildWithEvaluator(func(model interface{}, e *Evaluator) error {
+			got = e.Executor()
+			return nil
+		}),
+	)
+
+	err := Build(userFactory, WithExecutor(executor)).To(&testUser{})
+	if err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if got != executor {
+		t.Errorf("Evaluator.Executor()=%v, want the executor set by WithExecutor", got)
+	}
+}
+
+func TestCreateRelatedRowInTransaction(t *testing.T) {
+	type testAccount struct {
+		ID   int64  `factory:"id,primary"`
+		Name string `factory:"name"`
+	}
+
+	db, database := openTestDB()
+	defer db.Close()
+
+	accountFactory := def.NewFactory(testAccount{}, "accounts",
+		def.SequenceField("ID", 1, func(n int64) (interface{}, error) {
+			return n, nil
+		}),
+		def.Field("Name", "account name"),
+		def.AfterCreateWithEvaluator(func(model interface{}, e *Evaluator) error {
+			account := model.(*testAccount)
+			_, err := e.Executor().ExecContext(e.Context(), "INSERT INTO account_logs (account_id,action) VALUES (?,?)", account.ID, "created")
+			return err
+		}),
+	)
+
+	// Test the callback inserts the related row in the transaction set by WithExecutor
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin failed with error: %v", err)
+	}
+	account := &testAccount{}
+	if err := Create(accountFactory, WithExecutor(tx)).To(account); err != nil {
+		t.Fatalf("Create failed with error: %v", err)
+	}
+	rows := database.rows("account_logs")
+	if len(rows) != 1 || rows[0]["account_id"] != account.ID || rows[0]["action"] != "created" {
+		t.Errorf("Create with callback in transaction failed with log rows %v, want the row of account_id=%d", rows, account.ID)
+	}
+
+	// Test the related row is rolled back with the model instance
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback failed with error: %v", err)
+	}
+	if rows := database.rows("accounts"); len(rows) != 0 {
+		t.Errorf("Rollback failed with account rows %v, want no rows", rows)
+	}
+	if rows := database.rows("account_logs"); len(rows) != 0 {
+		t.Errorf("Rollback failed with log rows %v, want no rows", rows)
+	}
+}
@@ -0,0 +1,187 @@
+package factory
+
+import (
+	"fmt"
+	"sync"
+)
+
+const (
+	duplicateStrategyErr = "duplicate registration of strategy %s"
+	undefinedStrategyErr = "undefined strategy %s"
+	invalidBaseErr       = "invalid base strategy %s of strategy %s, want one of build, create and stub"
+	invalidResultErr     = "strategy %s returns result (type %v), want type *%s"
+	nilStrategyErr       = "strategy %s is nil"
+)
+
+// The phases of callbacks passed to CallbackStrategy.
+const (
+	CallbackBeforeBuild  = "before_build"
+	CallbackAfterBuild   = "after_build"
+	CallbackBeforeCreate = "before_create"
+	CallbackAfterCreate  = "after_create"
+	CallbackAfterStub    = "after_stub"
+)
+
+// Strategy is the interface of user-defined strategies, which can be registered by RegisterStrategy
+// and used by Generate and GenerateSlice.
+//
+// A user-defined strategy reuses the blueprint evaluation of a built-in strategy, which is named by Base.
+// The base strategy decides how the associations are generated, which callbacks are executed
+// and whether the model instance is saved into database.
+// These can be customized by implementing AssociationStrategy, CallbackStrategy and PersistStrategy.
+// Then Result is called with the model instance generated by the base strategy, and returns the final instance.
+// Result can read the transient fields and the fields of the model instance by the Evaluator.
+type Strategy interface {
+	// Base returns the name of the built-in strategy: StrategyBuild, StrategyCreate or StrategyStub.
+	Base() string
+	// Result completes the model instance generated by the base strategy.
+	// It must return a pointer of the model struct.
+	Result(instance interface{}, e *Evaluator) (interface{}, error)
+}
+
+// AssociationStrategy can be implemented by user-defined strategies to choose how associations are generated.
+type AssociationStrategy interface {
+	// Association returns the strategy to generate association name, like StrategyBuild or StrategyCreate.
+	// The strategy defined by the factory is used if it returns "".
+	Association(name string) string
+}
+
+// CallbackStrategy can be implemented by user-defined strategies to hook the callback phases.
+type CallbackStrategy interface {
+	// Callback is called in each phase, like CallbackAfterBuild, after the callbacks of the factory.
+	// The phases are the ones executed by the base strategy.
+	Callback(phase string, instance interface{}, e *Evaluator) error
+}
+
+// PersistStrategy can be implemented by user-defined strategies based on StrategyCreate to save model instances.
+type PersistStrategy interface {
+	// Persist saves the model instance by db instead of inserting it into the table of the factory.
+	Persist(db Executor, instance interface{}, e *Evaluator) error
+}
+
+var (
+	strategies    = map[string]Strategy{}
+	strategiesMux sync.RWMutex
+)
+
+// RegisterStrategy registers a user-defined strategy by name.
+// It returns error if the name is one of the built-in strategies or has been registered,
+// the strategy is nil, or the base of the strategy isn't a built-in strategy.
+//
+// type publishStrategy struct{ bus *EventBus }
+//
+// func (s publishStrategy) Base() string { return StrategyCreate }
+//
+// func (s publishStrategy) Result(instance interface{}, e *Evaluator) (interface{}, error) {
+// 	return instance, s.bus.Publish(instance)
+// }
+//
+// err := RegisterStrategy("publish", publishStrategy{bus})
+// err = Generate("publish", UserFactory).To(user)
+//
+func RegisterStrategy(name string, s Strategy) error {
+	if isBuiltinStrategy(name) || name == StrategyDelete {
+		return fmt.Errorf(duplicateStrategyErr, name)
+	}
+	if s == nil {
+		return fmt.Errorf(nilStrategyErr, name)
+	}
+	if base := s.Base(); !isBuiltinStrategy(base) {
+		return fmt.Errorf(invalidBaseErr, base, name)
+	}
+
+	strategiesMux.Lock()
+	defer strategiesMux.Unlock()
+
+	if _, ok := strategies[name]; ok {
+		return fmt.Errorf(duplicateStrategyErr, name)
+	}
+	strategies[name] = s
+	return nil
+}
+
+// Generate generates an instance from a factory by the strategy registered by name.
+// The built-in strategies StrategyBuild, StrategyCreate and StrategyStub can be used too,
+// which are the same as Build, Create and BuildStubbed.
+func Generate(name string, f *Factory, opts ...factoryOption) to {
+	s, bp, err := newStrategyBlueprint(name, f, opts)
+	if s == nil {
+		return &errorTo{err: err}
+	}
+
+	switch s.Base() {
+	case StrategyCreate:
+		return &createTo{blueprint: bp, dbConnection: bp.dbExecutor(), err: err}
+	case StrategyStub:
+		return &stubTo{blueprint: bp, err: err}
+	default:
+		return &buildTo{blueprint: bp, err: err}
+	}
+}
+
+// GenerateSlice generates a slice of instances from a factory by the strategy registered by name.
+func GenerateSlice(name string, f *Factory, count int, opts ...factoryOption) to {
+	s, bp, err := newStrategyBlueprint(name, f, opts)
+	if s == nil {
+		return &errorTo{err: err}
+	}
+
+	switch s.Base() {
+	case StrategyCreate:
+		return &createSliceTo{blueprint: bp, count: count, dbConnection: bp.dbExecutor(), err: err}
+	case StrategyStub:
+		return &stubSliceTo{blueprint: bp, count: count, err: err}
+	default:
+		return &buildSliceTo{blueprint: bp, count: count, err: err}
+	}
+}
+
+// newStrategyBlueprint creates the blueprint of factory f completed by the strategy registered by name.
+// It returns nil strategy if the strategy isn't registered.
+func newStrategyBlueprint(name string, f *Factory, opts []factoryOption) (Strategy, *blueprint, error) {
+	s, err := lookupStrategy(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bp := newDefaultBlueprint(f)
+	if s.Base() == StrategyCreate {
+		bp.table = newTable(f)
+	}
+	if !isBuiltinStrategy(name) {
+		bp.strategy = name
+		bp.custom = s
+	}
+
+	return s, bp, applyFactoryOptions(bp, opts)
+}
+
+// builtinStrategy represents the built-in strategies, which complete nothing.
+type builtinStrategy string
+
+func (s builtinStrategy) Base() string {
+	return string(s)
+}
+
+func (s builtinStrategy) Result(instance interface{}, e *Evaluator) (interface{}, error) {
+	return instance, nil
+}
+
+func isBuiltinStrategy(name string) bool {
+	return name == StrategyBuild || name == StrategyCreate || name == StrategyStub
+}
+
+func lookupStrategy(name string) (Strategy, error) {
+	if isBuiltinStrategy(name) {
+		return builtinStrategy(name), nil
+	}
+
+	strategiesMux.RLock()
+	defer strategiesMux.RUnlock()
+
+	s, ok := strategies[name]
+	if !ok {
+		return nil, fmt.Errorf(undefinedStrategyErr, name)
+	}
+	return s, nil
+}
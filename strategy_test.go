@@ -0,0 +1,201 @@
+package factory_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	. "github.com/nauyey/factory"
+	"github.com/nauyey/factory/def"
+)
+
+// testPublishStrategy builds instances and publishes them.
+type testPublishStrategy struct {
+	published *[]interface{}
+}
+
+func (s testPublishStrategy) Base() string {
+	return StrategyBuild
+}
+
+func (s testPublishStrategy) Result(instance interface{}, e *Evaluator) (interface{}, error) {
+	if topic, _ := e.Transient("Topic").(string); topic != "" {
+		instance.(*testUser).Country = topic
+	}
+	*s.published = append(*s.published, instance)
+	return instance, nil
+}
+
+// testStrategyRuns makes the strategy names registered by tests unique across repeated runs.
+var testStrategyRuns int
+
+func testStrategyName(name string) string {
+	testStrategyRuns++
+	return fmt.Sprintf("%s %d", name, testStrategyRuns)
+}
+
+func TestGenerate(t *testing.T) {
+	published := []interface{}{}
+	publish := testStrategyName("test publish")
+	if err := RegisterStrategy(publish, testPublishStrategy{published: &published}); err != nil {
+		t.Fatalf("RegisterStrategy failed with error: %v", err)
+	}
+
+	userFactory := def.NewFactory(testUser{}, "",
+		def.Field("Name", "test name"),
+		def.Transient("Topic", ""),
+	)
+
+	// Test Generate with user-defined strategy
+	user := &testUser{}
+	if err := Generate(publish, userFactory, WithTransient("Topic", "users")).To(user); err != nil {
+		t.Fatalf("Generate failed with error: %v", err)
+	}
+	if user.Name != "test name" || user.Country != "users" {
+		t.Errorf("Generate failed with user=%+v", user)
+	}
+
+	users := []*testUser{}
+	if err := GenerateSlice(publish, userFactory, 2).To(&users); err != nil {
+		t.Fatalf("GenerateSlice failed with error: %v", err)
+	}
+	if len(users) != 2 || len(published) != 3 {
+		t.Errorf("GenerateSlice failed with %d users, %d published instances, want 2 users, 3 published instances", len(users), len(published))
+	}
+
+	// Test Generate with built-in strategy
+	if err := Generate(StrategyBuild, userFactory).To(user); err != nil {
+		t.Fatalf("Generate with built-in strategy failed with error: %v", err)
+	}
+	if len(published) != 3 {
+		t.Errorf("Generate with built-in strategy shouldn't publish instances")
+	}
+
+	// Test invalid registrations
+	if err := RegisterStrategy(publish, testPublishStrategy{published: &published}); err == nil {
+		t.Errorf("RegisterStrategy with duplicate name should return error")
+	}
+	if err := RegisterStrategy(StrategyCreate, testPublishStrategy{published: &published}); err == nil {
+		t.Errorf("RegisterStrategy with built-in name should return error")
+	}
+	if err := RegisterStrategy("nil strategy", nil); err == nil {
+		t.Errorf("RegisterStrategy with nil strategy should return error")
+	}
+
+	// Test Generate with undefined strategy
+	if err := Generate("undefined", userFactory).To(user); err == nil {
+		t.Errorf("Generate with undefined strategy should return error")
+	}
+}
+
+// testResultStrategy builds instances and returns result as the final instance.
+type testResultStrategy struct {
+	result interface{}
+}
+
+func (s testResultStrategy) Base() string {
+	return StrategyBuild
+}
+
+func (s testResultStrategy) Result(instance interface{}, e *Evaluator) (interface{}, error) {
+	return s.result, nil
+}
+
+func TestGenerateWithInvalidResult(t *testing.T) {
+	userFactory := def.NewFactory(testUser{}, "",
+		def.Field("Name", "test name"),
+	)
+
+	results := []interface{}{nil, (*testUser)(nil), testUser{}, &testBlog{}}
+	for _, result := range results {
+		name := testStrategyName("test result")
+		if err := RegisterStrategy(name, testResultStrategy{result: result}); err != nil {
+			t.Fatalf("RegisterStrategy failed with error: %v", err)
+		}
+
+		err := Generate(name, userFactory).To(&testUser{})
+		if err == nil || !strings.Contains(err.Error(), "want type *testUser") {
+			t.Errorf("Generate with result %#v failed with err=%v", result, err)
+		}
+		err = GenerateSlice(name, userFactory, 2).To(&[]*testUser{})
+		if err == nil || !strings.Contains(err.Error(), "want type *testUser") {
+			t.Errorf("GenerateSlice with result %#v failed with err=%v", result, err)
+		}
+	}
+}
+
+// testHookStrategy creates instances, builds their associations and saves them by itself.
+type testHookStrategy struct {
+	phases    *[]string
+	persisted *[]interface{}
+}
+
+func (s testHookStrategy) Base() string {
+	return StrategyCreate
+}
+
+func (s testHookStrategy) Result(instance interface{}, e *Evaluator) (interface{}, error) {
+	return instance, nil
+}
+
+func (s testHookStrategy) Association(name string) string {
+	return StrategyBuild
+}
+
+func (s testHookStrategy) Callback(phase string, instance interface{}, e *Evaluator) error {
+	*s.phases = append(*s.phases, phase)
+	return nil
+}
+
+func (s testHookStrategy) Persist(db Executor, instance interface{}, e *Evaluator) error {
+	*s.persisted = append(*s.persisted, instance)
+	return nil
+}
+
+func TestGenerateWithHooks(t *testing.T) {
+	phases := []string{}
+	persisted := []interface{}{}
+	name := testStrategyName("test hook")
+	if err := RegisterStrategy(name, testHookStrategy{phases: &phases, persisted: &persisted}); err != nil {
+		t.Fatalf("RegisterStrategy failed with error: %v", err)
+	}
+
+	userFactory := def.NewFactory(testUser{}, "",
+		def.Field("Name", "test name"),
+	)
+	blogFactory := def.NewFactory(testBlog{}, "",
+		def.Field("Title", "test title"),
+		def.Association("Author", "AuthorID", "ID", userFactory,
+			def.Strategy(StrategyCreate),
+		),
+		def.BeforeCreate(func(blog interface{}) error {
+			phases = append(phases, "factory before_create")
+			return nil
+		}),
+	)
+
+	executor := &testExecutor{}
+	blog := &testBlog{}
+	if err := Generate(name, blogFactory, WithExecutor(executor)).To(blog); err != nil {
+		t.Fatalf("Generate failed with error: %v", err)
+	}
+	if blog.Title != "test title" || blog.Author == nil || blog.Author.Name != "test name" {
+		t.Errorf("Generate failed with blog=%+v", blog)
+	}
+
+	// Test association hook
+	if len(executor.queries) != 0 {
+		t.Errorf("Generate should build associations, but executed queries %v", executor.queries)
+	}
+
+	// Test callback hook
+	want := []string{CallbackBeforeBuild, CallbackAfterBuild, "factory before_create", CallbackBeforeCreate, CallbackAfterCreate}
+	if fmt.Sprint(phases) != fmt.Sprint(want) {
+		t.Errorf("Generate failed with callback phases %v, want %v", phases, want)
+	}
+
+	// Test persistence hook
+	if len(persisted) != 1 || persisted[0].(*testBlog).Title != "test title" {
+		t.Errorf("Generate failed with persisted instances %v", persisted)
+	}
+}
@@ -0,0 +1,34 @@
+package factory
+
+import (
+	"reflect"
+	"strconv"
+	"sync/atomic"
+)
+
+// stubID is the last fake id used by stubbed instances.
+// The fake ids start from 1001, like factory_bot.
+var stubID int64 = 1000
+
+func nextStubID() int64 {
+	return atomic.AddInt64(&stubID, 1)
+}
+
+// stubInstancePrimaryKeys sets the primary key fields of instance with unique fake ids.
+// The primary key fields which have been set, and which are neither integers nor strings, are kept.
+func stubInstancePrimaryKeys(tbl *table, instance reflect.Value) {
+	for _, col := range tbl.getPrimaryColumns() {
+		field := instance.Field(col.originalModelIndex)
+		if !field.IsZero() {
+			continue
+		}
+
+		switch field.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			field.Set(reflect.ValueOf(nextStubID()).Convert(field.Type()))
+		case reflect.String:
+			field.Set(reflect.ValueOf(strconv.FormatInt(nextStubID(), 10)).Convert(field.Type()))
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package factory_test
+
+import (
+	"testing"
+
+	. "github.com/nauyey/factory"
+	"github.com/nauyey/factory/def"
+)
+
+type testStubUser struct {
+	ID   int64  `factory:"id,primary"`
+	Name string `factory:"name"`
+}
+
+type testStubPost struct {
+	ID       string `factory:"id,primary"`
+	Title    string `factory:"title"`
+	AuthorID int64  `factory:"author_id"`
+	Author   *testStubUser
+	Stubbed  bool
+}
+
+func TestBuildStubbed(t *testing.T) {
+	userFactory := def.NewFactory(testStubUser{}, "users",
+		def.Field("Name", "test name"),
+	)
+	postFactory := def.NewFactory(testStubPost{}, "posts",
+		def.Field("Title", "test title"),
+		def.Association("Author", "AuthorID", "ID", userFactory,
+			def.Strategy(StrategyCreate),
+		),
+		def.AfterStub(func(model interface{}) error {
+			model.(*testStubPost).Stubbed = true
+			return nil
+		}),
+	)
+
+	post := &testStubPost{}
+	if err := BuildStubbed(postFactory).To(post); err != nil {
+		t.Fatalf("BuildStubbed failed with error: %v", err)
+	}
+	if post.ID == "" || post.Title != "test title" || !post.Stubbed {
+		t.Errorf("BuildStubbed failed with post=%+v", post)
+	}
+	if post.Author == nil || post.Author.ID == 0 || post.AuthorID != post.Author.ID {
+		t.Fatalf("BuildStubbed failed with association author=%+v, AuthorID=%d", post.Author, post.AuthorID)
+	}
+
+	// Test the fake ids are unique
+	posts := []*testStubPost{}
+	if err := BuildStubbedSlice(postFactory, 3).To(&posts); err != nil {
+		t.Fatalf("BuildStubbedSlice failed with error: %v", err)
+	}
+	ids := map[string]bool{post.ID: true}
+	for _, post := range posts {
+		if ids[post.ID] {
+			t.Errorf("BuildStubbedSlice failed with duplicate ID=%s", post.ID)
+		}
+		ids[post.ID] = true
+	}
+
+	// Test the primary key set by the factory is kept
+	user := &testStubUser{}
+	if err := BuildStubbed(userFactory, WithField("ID", int64(7))).To(user); err != nil {
+		t.Fatalf("BuildStubbed failed with error: %v", err)
+	}
+	if user.ID != 7 {
+		t.Errorf("BuildStubbed failed with ID=%d, want ID=7", user.ID)
+	}
+}
@@ -22,8 +22,8 @@ func TestNewTable(t *testing.T) {
 	userFactory := &Factory{
 		ModelType: reflect.TypeOf(testUser{}),
 		Table:     "user_table",
-		FiledValues: map[string]interface{}{
-			"Name": "test name",
+		Fields: []*FieldDefinition{
+			{Name: "Name", Value: "test name"},
 		},
 	}
 
@@ -1,7 +1,6 @@
 package factory
 
 import (
-	"database/sql"
 	"fmt"
 	"reflect"
 )
@@ -9,6 +8,7 @@ import (
 const (
 	invalidTargetTypeErr      = "cannot use target (type *%v) as type *%v in func To"
 	invalidTargetSliceTypeErr = "cannot use target (type []*%v) as type []*%v in func To"
+	invalidInstanceTypeErr    = "cannot use instance (type %v) as type *%v in func To"
 )
 
 // to is the interface that wraps the basic To method.
@@ -19,30 +19,100 @@ type to interface {
 	To(target interface{}) error
 }
 
+// errorTo is returned by strategies which can't generate any instance.
+// Its To method always returns err.
+type errorTo struct {
+	err error
+}
+
+func (to *errorTo) To(target interface{}) error {
+	return to.err
+}
+
 type buildTo struct {
 	blueprint *blueprint
+	err       error
 }
 
 func (to *buildTo) To(target interface{}) error {
+	if to.err != nil {
+		return to.err
+	}
 	if err := checkTargetType(to.blueprint.factory.ModelType, target); err != nil {
 		return err
 	}
 
-	instanceIface, err := to.blueprint.build()
+	instanceIface, err := to.blueprint.generateElement(0, (*blueprint).build)
 	if err != nil {
 		return err
 	}
 
-	setValue(target, instanceIface)
-	return nil
+	return setValue(target, instanceIface)
 }
 
 type buildSliceTo struct {
 	blueprint *blueprint
 	count     int
+	err       error
 }
 
 func (to *buildSliceTo) To(target interface{}) error {
+	if to.err != nil {
+		return to.err
+	}
+	targetType, targetValue := targetTypeAndValue(target)
+	elemType, isPtrElem := elemTypeOf(targetType)
+
+	// check element type of target slice
+	if err := checkTargetSliceType(to.blueprint.factory.ModelType, elemType); err != nil {
+		return err
+	}
+
+	sliceValue := reflect.MakeSlice(targetType, 0, to.count)
+	for i := 0; i < to.count; i++ {
+		elemIface, err := to.blueprint.generateElement(i, (*blueprint).build)
+		if err != nil {
+			return err
+		}
+
+		sliceValue = appendSliceValue(sliceValue, isPtrElem, reflect.ValueOf(elemIface))
+	}
+	targetValue.Set(sliceValue)
+
+	return nil
+}
+
+type stubTo struct {
+	blueprint *blueprint
+	err       error
+}
+
+func (to *stubTo) To(target interface{}) error {
+	if to.err != nil {
+		return to.err
+	}
+	if err := checkTargetType(to.blueprint.factory.ModelType, target); err != nil {
+		return err
+	}
+
+	instanceIface, err := to.blueprint.generateElement(0, (*blueprint).stub)
+	if err != nil {
+		return err
+	}
+
+	return setValue(target, instanceIface)
+}
+
+type stubSliceTo struct {
+	blueprint *blueprint
+	count     int
+	err       error
+}
+
+func (to *stubSliceTo) To(target interface{}) error {
+	if to.err != nil {
+		return to.err
+	}
 	targetType, targetValue := targetTypeAndValue(target)
 	elemType, isPtrElem := elemTypeOf(targetType)
 
@@ -53,7 +123,7 @@ func (to *buildSliceTo) To(target interface{}) error {
 
 	sliceValue := reflect.MakeSlice(targetType, 0, to.count)
 	for i := 0; i < to.count; i++ {
-		elemIface, err := to.blueprint.build()
+		elemIface, err := to.blueprint.generateElement(i, (*blueprint).stub)
 		if err != nil {
 			return err
 		}
@@ -67,30 +137,41 @@ func (to *buildSliceTo) To(target interface{}) error {
 
 type createTo struct {
 	blueprint    *blueprint
-	dbConnection *sql.DB
+	dbConnection Executor
+	err          error
 }
 
 func (to *createTo) To(target interface{}) error {
+	if to.err != nil {
+		return to.err
+	}
 	if err := checkTargetType(to.blueprint.factory.ModelType, target); err != nil {
 		return err
 	}
 
-	instanceIface, err := to.blueprint.create(to.dbConnection)
+	instanceIface, err := to.blueprint.generateElement(0, to.create)
 	if err != nil {
 		return err
 	}
 
-	setValue(target, instanceIface)
-	return nil
+	return setValue(target, instanceIface)
+}
+
+func (to *createTo) create(bp *blueprint) (interface{}, error) {
+	return bp.create(to.dbConnection)
 }
 
 type createSliceTo struct {
 	blueprint    *blueprint
 	count        int
-	dbConnection *sql.DB
+	dbConnection Executor
+	err          error
 }
 
 func (to *createSliceTo) To(target interface{}) error {
+	if to.err != nil {
+		return to.err
+	}
 	targetType, targetValue := targetTypeAndValue(target)
 	elemType, isPtrElem := elemTypeOf(targetType)
 
@@ -101,7 +182,7 @@ func (to *createSliceTo) To(target interface{}) error {
 
 	sliceValue := reflect.MakeSlice(targetType, 0, to.count)
 	for i := 0; i < to.count; i++ {
-		elemIface, err := to.blueprint.create(to.dbConnection)
+		elemIface, err := to.blueprint.generateElement(i, to.create)
 		if err != nil {
 			return err
 		}
@@ -113,6 +194,10 @@ func (to *createSliceTo) To(target interface{}) error {
 	return nil
 }
 
+func (to *createSliceTo) create(bp *blueprint) (interface{}, error) {
+	return bp.create(to.dbConnection)
+}
+
 func targetTypeAndValue(target interface{}) (reflect.Type, reflect.Value) {
 	targetType := reflect.TypeOf(target)
 	targetValue := reflect.ValueOf(target)
@@ -148,9 +233,14 @@ func checkTargetSliceType(wantType, targetSliceElemType reflect.Type) error {
 	return nil
 }
 
-func setValue(dest interface{}, src interface{}) {
+func setValue(dest interface{}, src interface{}) error {
 	targetValue := reflect.ValueOf(dest).Elem()
-	targetValue.Set(reflect.ValueOf(src).Elem())
+	srcValue := reflect.ValueOf(src)
+	if srcValue.Kind() != reflect.Ptr || srcValue.IsNil() || srcValue.Elem().Type() != targetValue.Type() {
+		return fmt.Errorf(invalidInstanceTypeErr, reflect.TypeOf(src), targetValue.Type())
+	}
+	targetValue.Set(srcValue.Elem())
+	return nil
 }
 
 func appendSliceValue(sliceValue reflect.Value, isPtrElem bool, elemValue reflect.Value) reflect.Value {
@@ -0,0 +1,158 @@
+//go:build go1.18
+// +build go1.18
+
+// Package typed provides a generics-based API of factories.
+// The model instances are generated as *T, so there's no need to pass targets to To
+// and assert the types of model instances.
+//
+// var UserFactory = typed.Define[User]("user_table",
+// 	def.Field("Name", "test name"),
+// 	typed.Dynamic("Email", func(user *User) (string, error) {
+// 		return strings.ToLower(user.Name) + "@example.com", nil
+// 	}),
+// 	def.AfterBuild(typed.Callback(func(user *User) error {
+// 		// do something
+// 		return nil
+// 	})),
+// )
+//
+// user, err := typed.Build(UserFactory, factory.WithField("Age", int32(16)))
+// users, err := typed.CreateSlice(UserFactory, 3)
+//
+// It's implemented on top of the strategies of package factory, and it needs Go 1.18 or later.
+package typed
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/nauyey/factory"
+	"github.com/nauyey/factory/def"
+)
+
+const (
+	invalidFactoryTypeErr    = "cannot use factory of type %s as factory of type %v"
+	invalidGeneratorModelErr = "cannot use generator of model %v for field %s to define factory of %s"
+	invalidFieldValueTypeErr = "cannot use value (type %v) as type %v of field %s to define factory of %s"
+)
+
+// Factory represents a factory of model struct T.
+type Factory[T any] struct {
+	*factory.Factory
+}
+
+// Define defines a factory of model struct T like def.NewFactory.
+// It panics if there are any errors in the definition.
+func Define[T any](table string, opts ...def.Option) *Factory[T] {
+	var model T
+	return &Factory[T]{Factory: def.NewFactory(model, table, opts...)}
+}
+
+// DefineE defines a factory of model struct T like def.NewFactoryE.
+// It returns the errors in the definition instead of panicking.
+func DefineE[T any](table string, opts ...def.Option) (*Factory[T], error) {
+	var model T
+	f, err := def.NewFactoryE(model, table, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Factory[T]{Factory: f}, nil
+}
+
+// Of wraps factory f defined by def.NewFactory as a factory of model struct T.
+// It returns error if f isn't a factory of T.
+func Of[T any](f *factory.Factory) (*Factory[T], error) {
+	if modelType := reflect.TypeOf((*T)(nil)).Elem(); f.ModelType != modelType {
+		return nil, fmt.Errorf(invalidFactoryTypeErr, f.ModelType.Name(), modelType)
+	}
+	return &Factory[T]{Factory: f}, nil
+}
+
+// Build builds an instance of T from factory f like factory.Build.
+func Build[T any](f *Factory[T], opts ...factory.Option) (*T, error) {
+	model := new(T)
+	if err := factory.Build(f.Factory, opts...).To(model); err != nil {
+		return nil, err
+	}
+	return model, nil
+}
+
+// BuildSlice builds count instances of T from factory f like factory.BuildSlice.
+func BuildSlice[T any](f *Factory[T], count int, opts ...factory.Option) ([]*T, error) {
+	models := []*T{}
+	if err := factory.BuildSlice(f.Factory, count, opts...).To(&models); err != nil {
+		return nil, err
+	}
+	return models, nil
+}
+
+// Create creates an instance of T from factory f and stores it into database like factory.Create.
+func Create[T any](f *Factory[T], opts ...factory.Option) (*T, error) {
+	model := new(T)
+	if err := factory.Create(f.Factory, opts...).To(model); err != nil {
+		return nil, err
+	}
+	return model, nil
+}
+
+// CreateSlice creates count instances of T from factory f and stores them into database like factory.CreateSlice.
+func CreateSlice[T any](f *Factory[T], count int, opts ...factory.Option) ([]*T, error) {
+	models := []*T{}
+	if err := factory.CreateSlice(f.Factory, count, opts...).To(&models); err != nil {
+		return nil, err
+	}
+	return models, nil
+}
+
+// Delete deletes an instance of T from database like factory.Delete.
+func Delete[T any](f *Factory[T], model *T, opts ...factory.Option) error {
+	return factory.Delete(f.Factory, model, opts...)
+}
+
+// Callback converts a callback taking the model instance of type *T to factory.Callback,
+// which can be used in def.BeforeBuild, def.AfterBuild, def.BeforeCreate, def.AfterCreate and the other callbacks.
+func Callback[T any](callback func(model *T) error) factory.Callback {
+	return func(model interface{}) error {
+		return callback(model.(*T))
+	}
+}
+
+// Dynamic defines the value generator of a dynamic field like def.DynamicField with types.
+// The generator takes the model instance of type *T, and returns the value of type V,
+// which must be the type of the field. So there's no need to assert the type of the model instance.
+func Dynamic[T any, V any](name string, value func(model *T) (V, error)) def.Option {
+	return func(f *factory.Factory) error {
+		if modelType := reflect.TypeOf((*T)(nil)).Elem(); modelType != f.ModelType {
+			return fmt.Errorf(invalidGeneratorModelErr, modelType, name, f.ModelType.Name())
+		}
+
+		if fieldType, ok := structFieldType(f.ModelType, name); ok {
+			if valueType := reflect.TypeOf((*V)(nil)).Elem(); valueType != fieldType {
+				return fmt.Errorf(invalidFieldValueTypeErr, valueType, fieldType, name, f.ModelType.Name())
+			}
+		}
+
+		return def.DynamicField(name, func(model interface{}) (interface{}, error) {
+			return value(model.(*T))
+		})(f)
+	}
+}
+
+// structFieldType returns the type of field name, which can be a multilevel field like "Author.Name".
+func structFieldType(typ reflect.Type, name string) (reflect.Type, bool) {
+	for _, fieldName := range strings.Split(name, ".") {
+		if typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+		if typ.Kind() != reflect.Struct {
+			return nil, false
+		}
+		field, ok := typ.FieldByName(fieldName)
+		if !ok {
+			return nil, false
+		}
+		typ = field.Type
+	}
+	return typ, true
+}
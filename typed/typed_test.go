@@ -0,0 +1,136 @@
+//go:build go1.18
+// +build go1.18
+
+package typed_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/nauyey/factory"
+	"github.com/nauyey/factory/def"
+	"github.com/nauyey/factory/typed"
+)
+
+type testUser struct {
+	ID       int64
+	Name     string
+	NickName string
+	Age      int32
+}
+
+type testBlog struct {
+	ID    int64
+	Title string
+}
+
+func TestBuild(t *testing.T) {
+	userFactory := typed.Define[testUser]("",
+		def.SequenceField("ID", 1, func(n int64) (interface{}, error) {
+			return n, nil
+		}),
+		def.Field("Name", "test name"),
+		typed.Dynamic("NickName", func(user *testUser) (string, error) {
+			return "nick " + user.Name, nil
+		}),
+		def.AfterBuild(typed.Callback(func(user *testUser) error {
+			user.Age = 16
+			return nil
+		})),
+	)
+
+	user, err := typed.Build(userFactory, factory.WithField("Name", "new name"))
+	if err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if user.ID != 1 || user.Name != "new name" || user.NickName != "nick new name" || user.Age != 16 {
+		t.Errorf("Build failed with user=%+v", user)
+	}
+
+	users, err := typed.BuildSlice(userFactory, 3)
+	if err != nil {
+		t.Fatalf("BuildSlice failed with error: %v", err)
+	}
+	if len(users) != 3 {
+		t.Fatalf("BuildSlice failed with len=%d, want len=3", len(users))
+	}
+	for i, user := range users {
+		if user.ID != int64(i+2) || user.NickName != "nick test name" {
+			t.Errorf("BuildSlice failed with user=%+v", user)
+		}
+	}
+
+	// Test Build with option error
+	if _, err := typed.Build(userFactory, factory.WithField("Name", 1)); err == nil {
+		t.Errorf("Build with invalid field value should return error")
+	}
+}
+
+func TestDefineE(t *testing.T) {
+	if _, err := typed.DefineE[testUser]("", def.Field("Name", 1)); err == nil {
+		t.Errorf("DefineE with invalid field value should return error")
+	}
+
+	// Test typed.Dynamic with generator of another model type
+	_, err := typed.DefineE[testUser]("",
+		typed.Dynamic("Name", func(blog *testBlog) (string, error) {
+			return blog.Title, nil
+		}),
+	)
+	if err == nil {
+		t.Errorf("DefineE with generator of another model type should return error")
+	}
+
+	// Test typed.Dynamic with generator of another value type
+	_, err = typed.DefineE[testUser]("",
+		typed.Dynamic("Age", func(user *testUser) (int, error) {
+			return 16, nil
+		}),
+	)
+	if err == nil {
+		t.Errorf("DefineE with generator of another value type should return error")
+	}
+
+	// Test typed.Dynamic returning error
+	userFactory := typed.Define[testUser]("",
+		typed.Dynamic("Name", func(user *testUser) (string, error) {
+			return "", errors.New("dynamic error")
+		}),
+	)
+	if _, err := typed.Build(userFactory); err == nil || err.Error() != "dynamic error" {
+		t.Errorf("Build failed with error: %v, want \"dynamic error\"", err)
+	}
+}
+
+func TestOf(t *testing.T) {
+	userFactory := def.NewFactory(testUser{}, "",
+		def.Field("Name", "test name"),
+	)
+
+	typedFactory, err := typed.Of[testUser](userFactory)
+	if err != nil {
+		t.Fatalf("Of failed with error: %v", err)
+	}
+	user, err := typed.Build(typedFactory)
+	if err != nil {
+		t.Fatalf("Build failed with error: %v", err)
+	}
+	if user.Name != "test name" {
+		t.Errorf("Build failed with Name=%s, want Name=\"test name\"", user.Name)
+	}
+
+	if _, err := typed.Of[testBlog](userFactory); err == nil {
+		t.Errorf("Of with factory of another model type should return error")
+	}
+}
+
+func TestDelete(t *testing.T) {
+	userFactory := typed.Define[testUser]("")
+
+	// Test Delete with options
+	err := typed.Delete(userFactory, &testUser{}, factory.WithTraits("undefined"))
+	if err == nil || !strings.Contains(err.Error(), "undefined trait name undefined") {
+		t.Errorf("Delete with undefined trait failed with err=%v", err)
+	}
+}
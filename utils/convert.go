@@ -0,0 +1,156 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+)
+
+const (
+	nilValueErr        = "cannot use nil as type %v"
+	unconvertibleErr   = "cannot convert value (type %v) to type %v"
+	overflowErr        = "value %v overflows type %v"
+	fractionalFloatErr = "value %v of type %v has fractional part to convert to type %v"
+)
+
+// ConvertValue converts value to type typ, and returns the converted value.
+// The value is converted if:
+// 1. it's nil and typ is a nillable kind, like pointer, slice, map, interface, channel or function;
+// 2. it's assignable to typ;
+// 3. it's of the same kind of typ, like a string literal to a named string type;
+// 4. it's a number, and it can be converted to the number kind of typ without overflow or losing fractions;
+// 5. typ is a pointer, and value can be converted to the element type of typ by the rules above.
+// In this case, a new pointer to the converted value is returned.
+// It returns error if the value can't be converted.
+func ConvertValue(value interface{}, typ reflect.Type) (interface{}, error) {
+	if value == nil {
+		if !isNillable(typ.Kind()) {
+			return nil, fmt.Errorf(nilValueErr, typ)
+		}
+		return reflect.Zero(typ).Interface(), nil
+	}
+
+	converted, err := convertValue(reflect.ValueOf(value), typ)
+	if err != nil && typ.Kind() == reflect.Ptr {
+		// auto-address the value for pointer types
+		elem, elemErr := convertValue(reflect.ValueOf(value), typ.Elem())
+		if elemErr != nil {
+			return nil, err
+		}
+		ptr := reflect.New(typ.Elem())
+		ptr.Elem().Set(elem)
+		return ptr.Interface(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return converted.Interface(), nil
+}
+
+// ConvertibleType reports whether the values of type from may be converted to type typ by ConvertValue.
+// The values of numbers may still fail to convert because of overflows or fractions.
+func ConvertibleType(from, typ reflect.Type) bool {
+	if convertibleType(from, typ) {
+		return true
+	}
+	return typ.Kind() == reflect.Ptr && convertibleType(from, typ.Elem())
+}
+
+func convertibleType(from, typ reflect.Type) bool {
+	switch {
+	case from.AssignableTo(typ):
+		return true
+	case from.Kind() == typ.Kind() && from.ConvertibleTo(typ):
+		return true
+	}
+	return isNumber(from.Kind()) && isNumber(typ.Kind())
+}
+
+func convertValue(v reflect.Value, typ reflect.Type) (reflect.Value, error) {
+	if v.Type() == typ {
+		return v, nil
+	}
+	if v.Type().AssignableTo(typ) {
+		converted := reflect.New(typ).Elem()
+		converted.Set(v)
+		return converted, nil
+	}
+
+	switch {
+	case v.Kind() == typ.Kind() && v.Type().ConvertibleTo(typ):
+		return v.Convert(typ), nil
+	case isNumber(v.Kind()) && isNumber(typ.Kind()):
+		return convertNumber(v, typ)
+	}
+
+	return reflect.Value{}, fmt.Errorf(unconvertibleErr, v.Type(), typ)
+}
+
+// convertNumber converts number v to number type typ with overflow checks.
+func convertNumber(v reflect.Value, typ reflect.Type) (reflect.Value, error) {
+	if isFloat(v.Kind()) && !isFloat(typ.Kind()) {
+		if f := v.Float(); f != math.Trunc(f) {
+			return reflect.Value{}, fmt.Errorf(fractionalFloatErr, v.Interface(), v.Type(), typ)
+		}
+	}
+
+	converted := v.Convert(typ)
+	if !sameNumber(v, converted) {
+		return reflect.Value{}, fmt.Errorf(overflowErr, v.Interface(), typ)
+	}
+	return converted, nil
+}
+
+// sameNumber reports whether the numbers of v and converted are equal,
+// which is false if the conversion overflows.
+func sameNumber(v, converted reflect.Value) bool {
+	switch {
+	case isInt(v.Kind()) && isInt(converted.Kind()):
+		return v.Int() == converted.Int()
+	case isUint(v.Kind()) && isUint(converted.Kind()):
+		return v.Uint() == converted.Uint()
+	case isInt(v.Kind()) && isUint(converted.Kind()):
+		return v.Int() >= 0 && uint64(v.Int()) == converted.Uint()
+	case isUint(v.Kind()) && isInt(converted.Kind()):
+		return converted.Int() >= 0 && uint64(converted.Int()) == v.Uint()
+	case isFloat(v.Kind()) && isFloat(converted.Kind()):
+		// floats may lose precision, but mustn't overflow
+		return math.IsInf(v.Float(), 0) || !math.IsInf(converted.Float(), 0)
+	}
+
+	// conversions between integers and floats are checked by converting the result back
+	return converted.Convert(v.Type()).Interface() == v.Interface()
+}
+
+func isNillable(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface, reflect.Chan, reflect.Func:
+		return true
+	}
+	return false
+}
+
+func isNumber(kind reflect.Kind) bool {
+	return isInt(kind) || isUint(kind) || isFloat(kind)
+}
+
+func isInt(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func isUint(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+func isFloat(kind reflect.Kind) bool {
+	return kind == reflect.Float32 || kind == reflect.Float64
+}
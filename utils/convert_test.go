@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+type testStatus string
+
+func TestConvertValue(t *testing.T) {
+	name := "name"
+
+	for _, c := range []struct {
+		value interface{}
+		typ   reflect.Type
+		want  interface{}
+	}{
+		{int64(1), reflect.TypeOf(int64(0)), int64(1)},
+		{30, reflect.TypeOf(int32(0)), int32(30)},
+		{30, reflect.TypeOf(uint8(0)), uint8(30)},
+		{uint(30), reflect.TypeOf(0), 30},
+		{30, reflect.TypeOf(float64(0)), float64(30)},
+		{float64(30), reflect.TypeOf(int32(0)), int32(30)},
+		{0.5, reflect.TypeOf(float32(0)), float32(0.5)},
+		{"active", reflect.TypeOf(testStatus("")), testStatus("active")},
+		{nil, reflect.TypeOf(&name), (*string)(nil)},
+		{nil, reflect.TypeOf([]string{}), []string(nil)},
+		{"name", reflect.TypeOf((*interface{})(nil)).Elem(), "name"},
+	} {
+		got, err := ConvertValue(c.value, c.typ)
+		if err != nil {
+			t.Errorf("ConvertValue(%v, %v) failed with error: %v", c.value, c.typ, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) || reflect.TypeOf(got) != reflect.TypeOf(c.want) {
+			t.Errorf("ConvertValue(%v, %v) failed with %#v, want %#v", c.value, c.typ, got, c.want)
+		}
+	}
+
+	// Test auto-addressing for pointer types
+	got, err := ConvertValue("name", reflect.TypeOf(&name))
+	if err != nil {
+		t.Fatalf("ConvertValue failed with error: %v", err)
+	}
+	if ptr, ok := got.(*string); !ok || *ptr != "name" {
+		t.Errorf("ConvertValue failed with %#v, want pointer to \"name\"", got)
+	}
+	got, err = ConvertValue(16, reflect.TypeOf(new(int32)))
+	if err != nil {
+		t.Fatalf("ConvertValue failed with error: %v", err)
+	}
+	if ptr, ok := got.(*int32); !ok || *ptr != 16 {
+		t.Errorf("ConvertValue failed with %#v, want pointer to 16", got)
+	}
+
+	for _, c := range []struct {
+		value interface{}
+		typ   reflect.Type
+	}{
+		{nil, reflect.TypeOf(0)},
+		{300, reflect.TypeOf(int8(0))},
+		{-1, reflect.TypeOf(uint(0))},
+		{uint64(math.MaxUint64), reflect.TypeOf(int64(0))},
+		{1.5, reflect.TypeOf(0)},
+		{math.MaxFloat64, reflect.TypeOf(float32(0))},
+		{int64(math.MaxInt64), reflect.TypeOf(float64(0))},
+		{65, reflect.TypeOf("")},
+		{"30", reflect.TypeOf(0)},
+	} {
+		if got, err := ConvertValue(c.value, c.typ); err == nil {
+			t.Errorf("ConvertValue(%v, %v) should return error, but got %#v", c.value, c.typ, got)
+		}
+	}
+}
+
+func TestConvertibleType(t *testing.T) {
+	name := "name"
+
+	for _, c := range []struct {
+		from reflect.Type
+		typ  reflect.Type
+		want bool
+	}{
+		{reflect.TypeOf(int64(0)), reflect.TypeOf(int64(0)), true},
+		{reflect.TypeOf(0), reflect.TypeOf(uint8(0)), true},
+		{reflect.TypeOf(""), reflect.TypeOf(testStatus("")), true},
+		{reflect.TypeOf(""), reflect.TypeOf(&name), true},
+		{reflect.TypeOf(&name), reflect.TypeOf((*interface{})(nil)).Elem(), true},
+		{reflect.TypeOf(""), reflect.TypeOf(int64(0)), false},
+		{reflect.TypeOf(0), reflect.TypeOf(""), false},
+		{reflect.TypeOf(&name), reflect.TypeOf(""), false},
+	} {
+		if got := ConvertibleType(c.from, c.typ); got != c.want {
+			t.Errorf("ConvertibleType(%v, %v) failed with %t, want %t", c.from, c.typ, got, c.want)
+		}
+	}
+}